@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/ssetests"
+)
+
+const captureCommandName = "capture"
+
+type headerListFlag http.Header
+
+func (h headerListFlag) String() string { return "" }
+
+func (h headerListFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf(`header must be in the form "Name: value"`)
+	}
+	http.Header(h).Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	return nil
+}
+
+// runCaptureCommand records a stream from a real SSE server, in the format used by --replay.
+func runCaptureCommand(args []string) bool {
+	var (
+		url, out string
+		options  ssetests.CaptureOptions
+		debug    bool
+	)
+	options.Headers = make(http.Header)
+	fs := flag.NewFlagSet(captureCommandName, flag.ExitOnError)
+	fs.StringVar(&url, "url", "", "URL of the SSE stream to capture")
+	fs.StringVar(&out, "out", "", "file to write the capture to; its name should end in "+ssetests.CaptureFileSuffix)
+	fs.DurationVar(&options.Duration, "duration", time.Second*10, "how long to capture for, or 0 to wait until the server closes the stream")
+	fs.Var(headerListFlag(options.Headers), "header", `request header in the form "Name: value" (can specify more than one)`)
+	fs.BoolVar(&debug, "debug", false, "log each chunk as it is received")
+
+	if err := fs.Parse(args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fs.Usage()
+		return false
+	}
+	if url == "" || out == "" {
+		fmt.Fprintln(os.Stderr, "-url and -out are required")
+		fs.Usage()
+		return false
+	}
+
+	logger := framework.NullLogger()
+	if debug {
+		logger = log.New(os.Stdout, "", log.LstdFlags)
+	}
+	fmt.Printf("Capturing %s\n", url)
+	capture, err := ssetests.CaptureStream(url, options, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Capture failed: %s\n", err)
+		if len(capture.Chunks) == 0 {
+			return false
+		}
+	}
+	if err := capture.WriteFile(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Could not write capture file: %s\n", err)
+		return false
+	}
+	eventsFile := strings.TrimSuffix(out, ssetests.CaptureFileSuffix) + ssetests.ExpectedEventsFileSuffix
+	fmt.Printf("Wrote %d chunks to %s; expected events for this capture go in %s\n", len(capture.Chunks), out, eventsFile)
+	return err == nil
+}
@@ -0,0 +1,80 @@
+// Command testservice runs the reference test service from the testservice package, so that
+// the test harness can be run against it.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/testservice"
+)
+
+const defaultPort = 8000
+
+func main() {
+	fs := flag.NewFlagSet("", flag.ExitOnError)
+	port := fs.Int("port", defaultPort, "port that the test service will listen on")
+	debug := fs.Bool("debug", false, "enable debug logging")
+	suite := fs.String("suite", "client", `which test suite to serve: "client" or "server"`)
+	defect := fs.String("defect", "", "introduce a deliberate bug into the SSE client, for testing the tests")
+	if err := fs.Parse(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	knownDefects := testservice.AllDefects
+	if *suite == "server" {
+		knownDefects = testservice.AllServerDefects
+	}
+	if *defect != "" && !isKnownDefect(testservice.Defect(*defect), knownDefects) {
+		fmt.Fprintf(os.Stderr, "unknown defect %q; valid defects for the %s suite are:\n", *defect, *suite)
+		for _, d := range knownDefects {
+			fmt.Fprintf(os.Stderr, "  %s\n", d)
+		}
+		os.Exit(1)
+	}
+
+	logger := framework.NullLogger()
+	if *debug {
+		logger = log.New(os.Stdout, "", log.LstdFlags)
+	}
+
+	server := &http.Server{Addr: fmt.Sprintf(":%d", *port)}
+	stop := func() { _ = server.Close() }
+	switch *suite {
+	case "client":
+		server.Handler = testservice.NewService(testservice.ServiceOptions{
+			Defect: testservice.Defect(*defect),
+			Logger: logger,
+			OnStop: stop,
+		})
+	case "server":
+		server.Handler = testservice.NewServerService(testservice.ServerServiceOptions{
+			Defect: testservice.Defect(*defect),
+			Logger: logger,
+			OnStop: stop,
+		})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown suite %q\n", *suite)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Reference test service listening on port %d\n", *port)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func isKnownDefect(defect testservice.Defect, knownDefects []testservice.Defect) bool {
+	for _, d := range knownDefects {
+		if d == defect {
+			return true
+		}
+	}
+	return false
+}
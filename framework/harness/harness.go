@@ -21,11 +21,13 @@ const httpListenerTimeout = time.Second * 10
 // It contains no domain-specific test logic, but only provides a general mechanism for test suites
 // to build on.
 type TestHarness struct {
-	testServiceBaseURL         string
-	testHarnessExternalBaseURL string
-	testServiceInfo            TestServiceInfo
-	mockEndpoints              *mockEndpointsManager
-	logger                     framework.Logger
+	testServiceBaseURL          string
+	testHarnessExternalHostname string
+	testHarnessPort             int
+	testHarnessExternalBaseURL  string
+	testServiceInfo             TestServiceInfo
+	mockEndpoints               *mockEndpointsManager
+	logger                      framework.Logger
 }
 
 // NewTestHarness creates a TestHarness instance, and verifies that the test service
@@ -46,10 +48,12 @@ func NewTestHarness(
 	externalBaseURL := fmt.Sprintf("http://%s:%d", testHarnessExternalHostname, testHarnessPort)
 
 	h := &TestHarness{
-		testServiceBaseURL:         testServiceBaseURL,
-		testHarnessExternalBaseURL: externalBaseURL,
-		mockEndpoints:              newMockEndpointsManager(externalBaseURL, debugLogger),
-		logger:                     debugLogger,
+		testServiceBaseURL:          testServiceBaseURL,
+		testHarnessExternalHostname: testHarnessExternalHostname,
+		testHarnessPort:             testHarnessPort,
+		testHarnessExternalBaseURL:  externalBaseURL,
+		mockEndpoints:               newMockEndpointsManager(externalBaseURL, debugLogger),
+		logger:                      debugLogger,
 	}
 
 	testServiceInfo, err := queryTestServiceInfo(testServiceBaseURL, statusQueryTimeout, startupOutput)
@@ -88,7 +92,22 @@ func (h *TestHarness) NewMockEndpoint(
 	if logger == nil {
 		logger = h.logger
 	}
-	return h.mockEndpoints.newMockEndpoint(handler, contextFn, logger)
+	return h.mockEndpoints.newMockEndpoint(handler, contextFn, false, logger)
+}
+
+// NewStreamingMockEndpoint is the same as NewMockEndpoint, except that the handler receives the
+// request body as it arrives, rather than after the test harness has read all of it. This allows
+// the handler to process a request that stays open for a long time, such as a stream of messages
+// from the test service. The Body property of IncomingRequestInfo is always nil for such requests.
+func (h *TestHarness) NewStreamingMockEndpoint(
+	handler http.Handler,
+	contextFn func(context.Context) context.Context,
+	logger framework.Logger,
+) *MockEndpoint {
+	if logger == nil {
+		logger = h.logger
+	}
+	return h.mockEndpoints.newMockEndpoint(handler, contextFn, true, logger)
 }
 
 func (h *TestHarness) serveHTTP(w http.ResponseWriter, r *http.Request) {
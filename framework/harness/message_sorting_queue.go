@@ -1,37 +1,120 @@
 package harness
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
+// maxReportedMissingCounters limits how many missing counters are listed in a MessageQueueGap, in
+// case a test service sends a wildly wrong counter.
+const maxReportedMissingCounters = 20
+
+// MessageSortingQueue delivers messages that were sent by a test service with sequential counters,
+// in counter order, regardless of the order in which they arrived. A message that arrives before
+// the ones that come before it is deferred until they have arrived.
 type MessageSortingQueue struct {
 	C           chan []byte
 	lastCounter int
 	deferred    []deferredMessage
+	closed      bool
+	closedCh    chan struct{}
 	lock        sync.Mutex
 	closeOnce   sync.Once
 }
 
 type deferredMessage struct {
-	counter int
-	message []byte
+	counter    int
+	message    []byte
+	receivedAt time.Time
+}
+
+// MessageQueueGap describes messages that a MessageSortingQueue has not received, even though it
+// has received messages with later counters.
+type MessageQueueGap struct {
+	// Missing is the list of missing counters, in order. If there are more than 20, only the
+	// first 20 are listed.
+	Missing []int
+
+	// MissingCount is the total number of missing counters.
+	MissingCount int
+
+	// DeferredCount is the number of messages that are waiting for the missing ones.
+	DeferredCount int
+
+	// Since is the time when the earliest of the waiting messages arrived.
+	Since time.Time
+}
+
+func (g MessageQueueGap) String() string {
+	counters := make([]string, 0, len(g.Missing))
+	for _, c := range g.Missing {
+		counters = append(counters, strconv.Itoa(c))
+	}
+	list := strings.Join(counters, ", ")
+	if g.MissingCount > len(g.Missing) {
+		list += fmt.Sprintf(", ... (%d in all)", g.MissingCount)
+	}
+	return fmt.Sprintf("callback counter(s) [%s] never arrived; %d later message(s) have been waiting for %s",
+		list, g.DeferredCount, time.Since(g.Since).Round(time.Millisecond))
 }
 
 func NewMessageSortingQueue(channelSize int) *MessageSortingQueue {
-	return &MessageSortingQueue{C: make(chan []byte, channelSize)}
+	return &MessageSortingQueue{C: make(chan []byte, channelSize), closedCh: make(chan struct{})}
 }
 
-func (q *MessageSortingQueue) Accept(counter int, message []byte) {
+// Accept adds a message to the queue. It returns an error, and discards the message, if the
+// counter is not a positive number or if a message with the same counter was already accepted.
+func (q *MessageSortingQueue) Accept(counter int, message []byte) error {
+	return q.AcceptBatch(counter, [][]byte{message})
+}
+
+// AcceptBatch adds a series of messages with consecutive counters to the queue, starting with
+// firstCounter. If any of the counters is not valid, it returns an error and discards all of the
+// messages. If the queue has been closed, the messages are quietly discarded.
+func (q *MessageSortingQueue) AcceptBatch(firstCounter int, messages [][]byte) error {
 	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed {
+		return nil
+	}
+	for i := range messages {
+		if err := q.checkCounter(firstCounter + i); err != nil {
+			return err
+		}
+	}
+	for i, message := range messages {
+		q.accept(firstCounter+i, message)
+	}
+	return nil
+}
+
+func (q *MessageSortingQueue) checkCounter(counter int) error {
+	if counter < 1 {
+		return fmt.Errorf("callback counter %d is not valid; counters must start at 1", counter)
+	}
+	if counter <= q.lastCounter {
+		return fmt.Errorf("received callback counter %d more than once", counter)
+	}
+	for _, d := range q.deferred {
+		if d.counter == counter {
+			return fmt.Errorf("received callback counter %d more than once", counter)
+		}
+	}
+	return nil
+}
+
+func (q *MessageSortingQueue) accept(counter int, message []byte) {
 	if counter > q.lastCounter+1 {
-		q.deferred = append(q.deferred, deferredMessage{counter: counter, message: message})
+		q.deferred = append(q.deferred, deferredMessage{counter: counter, message: message, receivedAt: time.Now()})
 		sort.Slice(q.deferred, func(i, j int) bool { return q.deferred[i].counter < q.deferred[j].counter })
-		q.lock.Unlock()
 		return
 	}
 	q.lastCounter = counter
-	q.C <- message
+	q.send(message)
 	for len(q.deferred) > 0 {
 		next := q.deferred[0]
 		if next.counter != q.lastCounter+1 {
@@ -39,9 +122,17 @@ func (q *MessageSortingQueue) Accept(counter int, message []byte) {
 		}
 		q.deferred = q.deferred[1:]
 		q.lastCounter++
-		q.C <- next.message
+		q.send(next.message)
+	}
+}
+
+// send delivers a message to the channel, unless the queue is closed while it is waiting for room.
+// The caller must hold the lock.
+func (q *MessageSortingQueue) send(message []byte) {
+	select {
+	case q.C <- message:
+	case <-q.closedCh:
 	}
-	q.lock.Unlock()
 }
 
 func (q *MessageSortingQueue) Deferred() [][]byte {
@@ -54,9 +145,39 @@ func (q *MessageSortingQueue) Deferred() [][]byte {
 	return ret
 }
 
+// Gap returns information about any messages that are holding up delivery of later messages. The
+// second return value is false if there are no such messages.
+func (q *MessageSortingQueue) Gap() (MessageQueueGap, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.deferred) == 0 {
+		return MessageQueueGap{}, false
+	}
+	gap := MessageQueueGap{DeferredCount: len(q.deferred), Since: q.deferred[0].receivedAt}
+	expected := q.lastCounter + 1
+	for _, d := range q.deferred {
+		if d.receivedAt.Before(gap.Since) {
+			gap.Since = d.receivedAt
+		}
+		for c := expected; c < d.counter && len(gap.Missing) < maxReportedMissingCounters; c++ {
+			gap.Missing = append(gap.Missing, c)
+		}
+		gap.MissingCount += d.counter - expected
+		expected = d.counter + 1
+	}
+	return gap, true
+}
+
+// Close closes the channel, so that anything reading from it will stop, and discards any messages
+// that are accepted afterward.
 func (q *MessageSortingQueue) Close() {
 	q.closeOnce.Do(func() {
+		// Closing closedCh first releases any sender that is waiting for room in the channel while
+		// holding the lock; after that, no one can send to the channel once we have the lock.
+		close(q.closedCh)
+		q.lock.Lock()
+		q.closed = true
 		close(q.C)
-		q.C = nil
+		q.lock.Unlock()
 	})
 }
@@ -31,7 +31,7 @@ func expectTestItems(t *testing.T, q *MessageSortingQueue, counters ...int) {
 				deferredList = append(deferredList, string(d))
 			}
 			require.Fail(t, "timed out waiting for item from queue",
-				"was waiting for item %d; deferred items were [%v]", strings.Join(deferredList, ","))
+				"was waiting for item %d; deferred items were [%v]", c, strings.Join(deferredList, ","))
 		}
 	}
 }
@@ -77,3 +77,103 @@ func TestMessageSortingQueueWithMessagesOutOfOrder(t *testing.T) {
 	expectTestItems(t, q, 4, 5, 6)
 	expectDeferredItems(t, q) // empty
 }
+
+func TestMessageSortingQueueRejectsDuplicateCounters(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+	acceptTestItems(q, 1, 2, 4)
+
+	assert.Error(t, q.Accept(2, []byte("duplicate")))
+	assert.Error(t, q.Accept(4, []byte("duplicate")))
+	expectDeferredItems(t, q, 4)
+
+	acceptTestItems(q, 3)
+	expectTestItems(t, q, 1, 2, 3, 4)
+	assert.Len(t, q.C, 0)
+}
+
+func TestMessageSortingQueueRejectsInvalidCounters(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+	assert.Error(t, q.Accept(0, []byte("zero")))
+	assert.Error(t, q.Accept(-1, []byte("negative")))
+	expectDeferredItems(t, q)
+	assert.Len(t, q.C, 0)
+}
+
+func TestMessageSortingQueueReportsGaps(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+	_, hasGap := q.Gap()
+	assert.False(t, hasGap)
+
+	startTime := time.Now()
+	acceptTestItems(q, 1, 3, 6)
+	gap, hasGap := q.Gap()
+	require.True(t, hasGap)
+	assert.Equal(t, []int{2, 4, 5}, gap.Missing)
+	assert.Equal(t, 3, gap.MissingCount)
+	assert.Equal(t, 2, gap.DeferredCount)
+	assert.False(t, gap.Since.Before(startTime))
+	assert.Contains(t, gap.String(), "[2, 4, 5]")
+
+	acceptTestItems(q, 2)
+	gap, hasGap = q.Gap()
+	require.True(t, hasGap)
+	assert.Equal(t, []int{4, 5}, gap.Missing)
+
+	acceptTestItems(q, 4, 5)
+	_, hasGap = q.Gap()
+	assert.False(t, hasGap)
+}
+
+func TestMessageSortingQueueLimitsReportedGap(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+	acceptTestItems(q, 1000000)
+	gap, hasGap := q.Gap()
+	require.True(t, hasGap)
+	assert.Len(t, gap.Missing, maxReportedMissingCounters)
+	assert.Equal(t, 999999, gap.MissingCount)
+	assert.Contains(t, gap.String(), "(999999 in all)")
+}
+
+func TestMessageSortingQueueWithBatches(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+
+	require.NoError(t, q.AcceptBatch(4, [][]byte{fakeItemData(4), fakeItemData(5)}))
+	expectDeferredItems(t, q, 4, 5)
+
+	require.NoError(t, q.AcceptBatch(1, [][]byte{fakeItemData(1), fakeItemData(2), fakeItemData(3)}))
+	expectTestItems(t, q, 1, 2, 3, 4, 5)
+	expectDeferredItems(t, q) // empty
+}
+
+func TestMessageSortingQueueRejectsBatchWithDuplicateCounter(t *testing.T) {
+	q := NewMessageSortingQueue(10)
+	acceptTestItems(q, 3)
+
+	assert.Error(t, q.AcceptBatch(1, [][]byte{fakeItemData(1), fakeItemData(2), fakeItemData(3)}))
+	expectDeferredItems(t, q, 3)
+	assert.Len(t, q.C, 0)
+}
+
+func TestMessageSortingQueueDiscardsMessagesAfterClose(t *testing.T) {
+	q := NewMessageSortingQueue(1)
+	acceptTestItems(q, 1)
+
+	// This one has to wait for room in the channel, until the queue is closed.
+	acceptedCh := make(chan error, 1)
+	go func() { acceptedCh <- q.Accept(2, fakeItemData(2)) }()
+	time.Sleep(time.Millisecond * 50)
+	q.Close()
+	select {
+	case err := <-acceptedCh:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "Accept was still blocked after Close")
+	}
+
+	assert.NoError(t, q.Accept(3, fakeItemData(3)))
+	var received []string
+	for item := range q.C {
+		received = append(received, string(item))
+	}
+	assert.Equal(t, []string{string(fakeItemData(1))}, received)
+}
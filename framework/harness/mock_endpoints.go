@@ -32,6 +32,7 @@ type MockEndpoint struct {
 	basePath    string
 	handler     http.Handler
 	contextFn   func(context.Context) context.Context
+	streamBody  bool
 	newConns    chan IncomingRequestInfo
 	activeConn  *IncomingRequestInfo
 	logger      framework.Logger
@@ -46,6 +47,11 @@ type IncomingRequestInfo struct {
 	Method  string
 	Body    []byte
 	Context context.Context
+
+	// RequestURI is the path and query of the request, relative to the endpoint's base URL, exactly
+	// as the test service sent them; for instance, "/sub/path?a=b%20c". It is empty if the request
+	// was for the base URL itself.
+	RequestURI string
 }
 
 func newMockEndpointsManager(externalBaseURL string, logger framework.Logger) *mockEndpointsManager {
@@ -59,17 +65,19 @@ func newMockEndpointsManager(externalBaseURL string, logger framework.Logger) *m
 func (m *mockEndpointsManager) newMockEndpoint(
 	handler http.Handler,
 	contextFn func(context.Context) context.Context,
+	streamBody bool,
 	logger framework.Logger,
 ) *MockEndpoint {
 	if logger == nil {
 		logger = m.logger
 	}
 	e := &MockEndpoint{
-		owner:     m,
-		handler:   handler,
-		contextFn: contextFn,
-		newConns:  make(chan IncomingRequestInfo, 100),
-		logger:    logger,
+		owner:      m,
+		handler:    handler,
+		contextFn:  contextFn,
+		streamBody: streamBody,
+		newConns:   make(chan IncomingRequestInfo, 100),
+		logger:     logger,
 	}
 	m.lock.Lock()
 	m.lastEndpointID++
@@ -108,7 +116,7 @@ func (m *mockEndpointsManager) serveHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	var body []byte
-	if r.Body != nil {
+	if r.Body != nil && !e.streamBody {
 		data, err := ioutil.ReadAll(r.Body)
 		_ = r.Body.Close()
 		if err != nil {
@@ -133,10 +141,11 @@ func (m *mockEndpointsManager) serveHTTP(w http.ResponseWriter, r *http.Request)
 	}
 
 	incoming := &IncomingRequestInfo{
-		Headers: r.Header,
-		Method:  r.Method,
-		Body:    body,
-		Context: ctx,
+		Headers:    r.Header,
+		Method:     r.Method,
+		Body:       body,
+		Context:    ctx,
+		RequestURI: relativeRequestURI(r, e.basePath),
 	}
 
 	e.lock.Lock()
@@ -153,6 +162,32 @@ func (m *mockEndpointsManager) serveHTTP(w http.ResponseWriter, r *http.Request)
 	e.handler.ServeHTTP(w, transformedReq)
 }
 
+// relativeRequestURI returns the part of the raw request target that follows basePath. We use the
+// raw target, rather than r.URL, so that any percent-encoding is preserved as it was sent.
+func relativeRequestURI(r *http.Request, basePath string) string {
+	target := r.RequestURI
+	if !strings.HasPrefix(target, "/") {
+		// The target is an absolute URL, which clients only send to a proxy; skip the scheme and host.
+		if pos := strings.Index(target, "://"); pos >= 0 {
+			target = target[pos+len("://"):]
+			if pos = strings.IndexAny(target, "/?"); pos >= 0 {
+				target = target[pos:]
+			} else {
+				target = ""
+			}
+		}
+	}
+	if strings.HasPrefix(target, basePath) {
+		return strings.TrimPrefix(target, basePath)
+	}
+	// The base path itself was percent-encoded in some way, so fall back to the parsed URL.
+	relative := strings.TrimPrefix(r.URL.EscapedPath(), basePath)
+	if r.URL.RawQuery != "" || r.URL.ForceQuery {
+		relative += "?" + r.URL.RawQuery
+	}
+	return relative
+}
+
 // BaseURL returns the base path of the mock endpoint.
 func (e *MockEndpoint) BaseURL() string {
 	return e.owner.externalBaseURL + e.basePath
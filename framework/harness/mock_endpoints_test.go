@@ -1,7 +1,9 @@
 package harness
 
 import (
+	"bufio"
 	"bytes"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,17 +14,18 @@ import (
 	"github.com/launchdarkly/go-test-helpers/v2/httphelpers"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMockEndpointServesRequest(t *testing.T) {
 	m := newMockEndpointsManager("http://testharness:9999", framework.NullLogger())
 
 	handler1 := httphelpers.HandlerWithStatus(200)
-	e1 := m.newMockEndpoint(handler1, nil, framework.NullLogger())
+	e1 := m.newMockEndpoint(handler1, nil, false, framework.NullLogger())
 	assert.Equal(t, "http://testharness:9999/endpoints/1", e1.BaseURL())
 
 	handler2 := httphelpers.HandlerWithStatus(204)
-	e2 := m.newMockEndpoint(handler2, nil, framework.NullLogger())
+	e2 := m.newMockEndpoint(handler2, nil, false, framework.NullLogger())
 	assert.Equal(t, "http://testharness:9999/endpoints/2", e2.BaseURL())
 
 	rr1 := httptest.NewRecorder()
@@ -40,7 +43,7 @@ func TestMockEndpointReceivesSubpath(t *testing.T) {
 	m := newMockEndpointsManager("http://testharness:9999", framework.NullLogger())
 
 	handler, requests := httphelpers.RecordingHandler(httphelpers.HandlerWithStatus(200))
-	e := m.newMockEndpoint(handler, nil, framework.NullLogger())
+	e := m.newMockEndpoint(handler, nil, false, framework.NullLogger())
 	assert.Equal(t, "http://testharness:9999/endpoints/1", e.BaseURL())
 
 	for _, subpath := range []string{"", "/", "/sub/path"} {
@@ -56,10 +59,31 @@ func TestMockEndpointReceivesSubpath(t *testing.T) {
 	}
 }
 
+func TestMockEndpointRecordsRequestURI(t *testing.T) {
+	m := newMockEndpointsManager("http://testharness:9999", framework.NullLogger())
+	e := m.newMockEndpoint(httphelpers.HandlerWithStatus(200), nil, false, framework.NullLogger())
+
+	for _, relativeURI := range []string{"", "/", "?a=b", "/sub/path", "/sub/a%20b?c=d%26e&f=%E2%82%AC"} {
+		for _, target := range []string{e.basePath + relativeURI, e.BaseURL() + relativeURI} {
+			// httptest.NewRequest, unlike http.NewRequest, sets RequestURI as a server would.
+			m.serveHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+			cxn, err := e.AwaitConnection(time.Second)
+			require.NoError(t, err)
+			assert.Equal(t, relativeURI, cxn.RequestURI, "target: %s", target)
+		}
+	}
+
+	r, _ := http.NewRequest("GET", e.BaseURL()+"/sub/a%20b?c=d", nil)
+	m.serveHTTP(httptest.NewRecorder(), r)
+	cxn, err := e.AwaitConnection(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "/sub/a%20b?c=d", cxn.RequestURI)
+}
+
 func TestMockEndpointConnectionInfo(t *testing.T) {
 	m := newMockEndpointsManager("http://testharness:9999", framework.NullLogger())
 	handler := httphelpers.HandlerWithStatus(200)
-	e := m.newMockEndpoint(handler, nil, framework.NullLogger())
+	e := m.newMockEndpoint(handler, nil, false, framework.NullLogger())
 
 	_, err := e.AwaitConnection(time.Millisecond * 50)
 	assert.Error(t, err)
@@ -82,3 +106,38 @@ func TestMockEndpointConnectionInfo(t *testing.T) {
 	assert.Equal(t, "POST", cxn2.Method)
 	assert.Equal(t, []byte("content"), cxn2.Body)
 }
+
+func TestStreamingMockEndpointPassesBodyThroughAsItArrives(t *testing.T) {
+	m := newMockEndpointsManager("http://testharness:9999", framework.NullLogger())
+	linesCh := make(chan string, 10)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			linesCh <- scanner.Text()
+		}
+		close(linesCh)
+	})
+	e := m.newMockEndpoint(handler, nil, true, framework.NullLogger())
+
+	bodyReader, bodyWriter := io.Pipe()
+	r, _ := http.NewRequest("POST", e.BaseURL(), bodyReader)
+	go m.serveHTTP(httptest.NewRecorder(), r)
+
+	_, _ = bodyWriter.Write([]byte("line1\n"))
+	select {
+	case line := <-linesCh:
+		assert.Equal(t, "line1", line)
+	case <-time.After(time.Second):
+		require.Fail(t, "timed out waiting for handler to receive the first line")
+	}
+
+	_, _ = bodyWriter.Write([]byte("line2\n"))
+	_ = bodyWriter.Close()
+	assert.Equal(t, "line2", <-linesCh)
+	_, open := <-linesCh
+	assert.False(t, open)
+
+	cxn, err := e.AwaitConnection(time.Second)
+	require.NoError(t, err)
+	assert.Nil(t, cxn.Body)
+}
@@ -0,0 +1,297 @@
+package harness
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+)
+
+const (
+	proxyDialTimeout = time.Second * 5
+	proxyBufferSize  = 8192
+)
+
+// ProxyOptions contains optional parameters for NewProxy.
+type ProxyOptions struct {
+	// Username and Password, if Username is not empty, are the credentials that clients must send
+	// with basic authentication in a Proxy-Authorization header. Requests without them get a 407
+	// response.
+	Username string
+	Password string
+}
+
+// Proxy is an HTTP forward proxy that the test service can be told to use. It forwards requests
+// whose target is an absolute URL, and tunnels connections for CONNECT requests.
+//
+// Unlike a MockEndpoint, it has its own listener, since a proxy is identified only by its host and
+// port. The test service must be able to reach the test harness on any port for this to work.
+type Proxy struct {
+	url       string
+	options   ProxyOptions
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+	requests  chan ProxiedRequest
+	tunnels   map[net.Conn]struct{}
+	aliases   map[string]string
+	logger    framework.Logger
+	lock      sync.Mutex
+	closing   sync.Once
+}
+
+// ProxiedRequest contains information about a request that was received by a Proxy.
+type ProxiedRequest struct {
+	// Method is the request method, which is "CONNECT" for a tunnel.
+	Method string
+
+	// Target is the absolute URL of the request, or the host and port for a CONNECT request.
+	Target string
+
+	// Headers are the request headers, including any Proxy-Authorization header.
+	Headers http.Header
+
+	// Authorized is true if the request was allowed through the proxy. It is only false if the
+	// proxy requires credentials and the request did not have the right ones.
+	Authorized bool
+}
+
+// NewProxy starts a forward proxy on a new port.
+func (h *TestHarness) NewProxy(options ProxyOptions, logger framework.Logger) (*Proxy, error) {
+	if logger == nil {
+		logger = h.logger
+	}
+	// Requests to our mock endpoints use our external hostname, which we might not be able to
+	// resolve ourselves, for instance if it is the name of a Docker host.
+	externalAddr := fmt.Sprintf("%s:%d", h.testHarnessExternalHostname, h.testHarnessPort)
+	aliases := map[string]string{externalAddr: fmt.Sprintf("localhost:%d", h.testHarnessPort)}
+	return newProxy(h.testHarnessExternalHostname, aliases, options, logger)
+}
+
+// newProxy creates a Proxy. Any target address ("host:port") that is a key in aliases is replaced
+// by the corresponding value when the proxy connects to it.
+func newProxy(
+	externalHostname string,
+	aliases map[string]string,
+	options ProxyOptions,
+	logger framework.Logger,
+) (*Proxy, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, err
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	p := &Proxy{
+		url:      fmt.Sprintf("http://%s:%d", externalHostname, port),
+		options:  options,
+		listener: listener,
+		requests: make(chan ProxiedRequest, 100),
+		tunnels:  make(map[net.Conn]struct{}),
+		aliases:  aliases,
+		logger:   logger,
+	}
+	p.transport = &http.Transport{
+		Proxy:              nil,
+		DialContext:        p.dial,
+		DisableCompression: true,
+	}
+	p.server = &http.Server{Handler: http.HandlerFunc(p.serveHTTP)}
+	go func() {
+		_ = p.server.Serve(listener)
+	}()
+	return p, nil
+}
+
+// URL returns the base URL of the proxy, without any credentials.
+func (p *Proxy) URL() string {
+	return p.url
+}
+
+// AwaitRequest waits for a request to the proxy.
+func (p *Proxy) AwaitRequest(timeout time.Duration) (ProxiedRequest, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case r := <-p.requests:
+		return r, nil
+	case <-deadline.C:
+		return ProxiedRequest{}, fmt.Errorf("timed out waiting for a request to the proxy at %s", p.url)
+	}
+}
+
+// Close stops the proxy and closes all of its connections, including tunnels.
+func (p *Proxy) Close() {
+	p.closing.Do(func() {
+		_ = p.server.Close()
+		p.transport.CloseIdleConnections()
+		p.lock.Lock()
+		for conn := range p.tunnels {
+			_ = conn.Close()
+		}
+		p.lock.Unlock()
+	})
+}
+
+func (p *Proxy) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	info := ProxiedRequest{Method: r.Method, Headers: r.Header, Authorized: p.isAuthorized(r)}
+	if r.Method == http.MethodConnect {
+		info.Target = r.Host
+	} else {
+		info.Target = r.URL.String()
+	}
+	p.logger.Printf("Proxy received %s %s", info.Method, info.Target)
+	select { // non-blocking push
+	case p.requests <- info:
+	default:
+		p.logger.Printf("Proxy request channel was full")
+	}
+
+	if !info.Authorized {
+		p.logger.Printf("Proxy rejected request with missing or incorrect credentials")
+		w.Header().Set("Proxy-Authenticate", `Basic realm="test harness proxy"`)
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		p.logger.Printf("Proxy received a request that was not for an absolute URL")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	p.forward(w, r)
+}
+
+func (p *Proxy) isAuthorized(r *http.Request) bool {
+	if p.options.Username == "" {
+		return true
+	}
+	auth := r.Header.Get("Proxy-Authorization")
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(auth[len(prefix):]))
+	return err == nil && string(decoded) == p.options.Username+":"+p.options.Password
+}
+
+// forward makes the request to its target and copies the response back, flushing as each piece of
+// the body arrives so that streaming responses are not held up.
+func (p *Proxy) forward(w http.ResponseWriter, r *http.Request) {
+	outReq := r.Clone(r.Context())
+	outReq.RequestURI = ""
+	removeHopByHopHeaders(outReq.Header)
+	resp, err := p.transport.RoundTrip(outReq)
+	if err != nil {
+		p.logger.Printf("Proxy request to %s failed: %s", r.URL, err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	removeHopByHopHeaders(resp.Header)
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	buf := make([]byte, proxyBufferSize)
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// tunnel connects to the target of a CONNECT request and then copies bytes in both directions
+// until either side closes its connection.
+func (p *Proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	target, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		p.logger.Printf("Proxy could not connect to %s: %s", r.Host, err)
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = target.Close()
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = target.Close()
+		p.logger.Printf("Proxy could not take over connection: %s", err)
+		return
+	}
+	p.lock.Lock()
+	p.tunnels[client] = struct{}{}
+	p.tunnels[target] = struct{}{}
+	p.lock.Unlock()
+	defer func() {
+		_ = client.Close()
+		_ = target.Close()
+		p.lock.Lock()
+		delete(p.tunnels, client)
+		delete(p.tunnels, target)
+		p.lock.Unlock()
+	}()
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n")); err != nil {
+		return
+	}
+	done := make(chan struct{}, 2)
+	go func() {
+		// Anything the client sent after the CONNECT request is already in the buffered reader.
+		_, _ = io.Copy(target, buffered)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, target)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func (p *Proxy) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	if alias, ok := p.aliases[addr]; ok {
+		addr = alias
+	}
+	dialer := net.Dialer{Timeout: proxyDialTimeout}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+func removeHopByHopHeaders(h http.Header) {
+	for _, name := range strings.Split(h.Get("Connection"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			h.Del(name)
+		}
+	}
+	for _, name := range []string{
+		"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+		"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+	} {
+		h.Del(name)
+	}
+}
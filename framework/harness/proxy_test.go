@@ -0,0 +1,135 @@
+package harness
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+
+	"github.com/launchdarkly/go-test-helpers/v2/httphelpers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startTestProxy(t *testing.T, options ProxyOptions) *Proxy {
+	p, err := newProxy("localhost", nil, options, framework.NullLogger())
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func clientForProxy(t *testing.T, proxyURL string, transport *http.Transport) *http.Client {
+	u, err := url.Parse(proxyURL)
+	require.NoError(t, err)
+	if transport == nil {
+		transport = &http.Transport{}
+	}
+	transport.Proxy = http.ProxyURL(u)
+	return &http.Client{Transport: transport}
+}
+
+func TestProxyForwardsRequest(t *testing.T) {
+	handler := httphelpers.HandlerWithResponse(200, nil, []byte("hello"))
+	httphelpers.WithServer(handler, func(server *httptest.Server) {
+		p := startTestProxy(t, ProxyOptions{})
+		client := clientForProxy(t, p.URL(), nil)
+
+		resp, err := client.Get(server.URL + "/path?a=b")
+		require.NoError(t, err)
+		body, _ := ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "hello", string(body))
+
+		r, err := p.AwaitRequest(time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, server.URL+"/path?a=b", r.Target)
+		assert.True(t, r.Authorized)
+	})
+}
+
+func TestProxyFlushesStreamingResponse(t *testing.T) {
+	releaseCh := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-releaseCh
+	})
+	httphelpers.WithServer(handler, func(server *httptest.Server) {
+		p := startTestProxy(t, ProxyOptions{})
+		client := clientForProxy(t, p.URL(), nil)
+
+		defer close(releaseCh) // the test server cannot shut down until the handler returns
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		defer func() { _ = resp.Body.Close() }()
+		readCh := make(chan string, 1)
+		go func() {
+			buf := make([]byte, 100)
+			n, _ := resp.Body.Read(buf)
+			readCh <- string(buf[:n])
+		}()
+		select {
+		case data := <-readCh:
+			assert.Equal(t, "first", data)
+		case <-time.After(time.Second):
+			assert.Fail(t, "timed out waiting for proxy to pass on the first part of the response")
+		}
+	})
+}
+
+func TestProxyRequiresCredentials(t *testing.T) {
+	handler := httphelpers.HandlerWithStatus(200)
+	httphelpers.WithServer(handler, func(server *httptest.Server) {
+		p := startTestProxy(t, ProxyOptions{Username: "user", Password: "pass"})
+
+		for _, creds := range []string{"", "user:wrong@"} {
+			client := clientForProxy(t, "http://"+creds+p.URL()[len("http://"):], nil)
+			resp, err := client.Get(server.URL)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, http.StatusProxyAuthRequired, resp.StatusCode, "credentials: %q", creds)
+			r, err := p.AwaitRequest(time.Second)
+			require.NoError(t, err)
+			assert.False(t, r.Authorized)
+		}
+
+		client := clientForProxy(t, "http://user:pass@"+p.URL()[len("http://"):], nil)
+		resp, err := client.Get(server.URL)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, 200, resp.StatusCode)
+		r, err := p.AwaitRequest(time.Second)
+		require.NoError(t, err)
+		assert.True(t, r.Authorized)
+	})
+}
+
+func TestProxyTunnelsConnectRequest(t *testing.T) {
+	handler := httphelpers.HandlerWithResponse(200, nil, []byte("secure hello"))
+	server := httptest.NewTLSServer(handler)
+	defer server.Close()
+
+	p := startTestProxy(t, ProxyOptions{Username: "user", Password: "pass"})
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	client := clientForProxy(t, "http://user:pass@"+p.URL()[len("http://"):], transport)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "secure hello", string(body))
+
+	r, err := p.AwaitRequest(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "CONNECT", r.Method)
+	assert.Equal(t, server.Listener.Addr().String(), r.Target)
+	assert.True(t, r.Authorized)
+}
@@ -99,6 +99,17 @@ func (h *TestHarness) NewTestServiceEntity(
 	entityParams interface{},
 	description string,
 	logger framework.Logger,
+) (*TestServiceEntity, error) {
+	return h.NewTestServiceEntityWithResponse(entityParams, description, logger, nil)
+}
+
+// NewTestServiceEntityWithResponse is the same as NewTestServiceEntity, except that it also
+// expects the test service to return a JSON response body, which will be parsed into responseOut.
+func (h *TestHarness) NewTestServiceEntityWithResponse(
+	entityParams interface{},
+	description string,
+	logger framework.Logger,
+	responseOut interface{},
 ) (*TestServiceEntity, error) {
 	if logger == nil {
 		logger = framework.NullLogger()
@@ -120,12 +131,18 @@ func (h *TestHarness) NewTestServiceEntity(
 	if err != nil {
 		return nil, err
 	}
+	var respBody []byte
+	if resp.Body != nil {
+		respBody, err = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		var message string
-		if resp.Body != nil {
-			data, _ = ioutil.ReadAll(resp.Body)
-			message = ": " + string(data)
-			_ = resp.Body.Close()
+		if len(respBody) != 0 {
+			message = ": " + string(respBody)
 		}
 		return nil, fmt.Errorf("unexpected response status %d from test service%s", resp.StatusCode, message)
 	}
@@ -136,6 +153,11 @@ func (h *TestHarness) NewTestServiceEntity(
 	if !strings.HasPrefix(resourceURL, "http:") {
 		resourceURL = h.testServiceBaseURL + resourceURL
 	}
+	if responseOut != nil {
+		if err := json.Unmarshal(respBody, responseOut); err != nil {
+			return nil, fmt.Errorf("malformed response from test service (%s): %s", err, string(respBody))
+		}
+	}
 
 	e := &TestServiceEntity{
 		resourceURL: resourceURL,
@@ -218,3 +240,35 @@ func (e *TestServiceEntity) SendCommandWithParams(
 	}
 	return nil
 }
+
+// Get sends a GET request to a subpath of the test service entity's resource URL, such as
+// "/messages?after=1", and parses the JSON response body into responseOut.
+func (e *TestServiceEntity) Get(
+	subpath string,
+	logger framework.Logger,
+	responseOut interface{},
+) error {
+	if logger == nil {
+		logger = e.logger
+	}
+	resp, err := http.DefaultClient.Get(e.resourceURL + subpath)
+	if err != nil {
+		return err
+	}
+	var body []byte
+	if resp.Body != nil {
+		body, err = ioutil.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("GET %s returned HTTP status %d", subpath, resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, responseOut); err != nil {
+		logger.Printf("Malformed response to GET %s: %s", subpath, string(body))
+		return fmt.Errorf("malformed response from test service (%s): %s", err, string(body))
+	}
+	return nil
+}
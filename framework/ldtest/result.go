@@ -11,8 +11,9 @@ type Results struct {
 }
 
 type TestResult struct {
-	TestID TestID
-	Errors []error
+	TestID  TestID
+	Errors  []error
+	Reports []string
 }
 
 func (r Results) OK() bool {
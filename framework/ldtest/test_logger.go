@@ -23,6 +23,12 @@ type TestLogger interface {
 	TestSkipped(id TestID, reason string)
 }
 
+// TestReportLogger is an optional interface for a TestLogger that can show the messages that a
+// test adds with T.Report.
+type TestReportLogger interface {
+	TestReport(id TestID, report string)
+}
+
 type nullTestLogger struct{}
 
 func (n nullTestLogger) TestStarted(TestID)                                  {}
@@ -45,6 +51,12 @@ func (c ConsoleTestLogger) TestError(id TestID, err error) {
 	}
 }
 
+func (c ConsoleTestLogger) TestReport(id TestID, report string) {
+	for _, line := range strings.Split(report, "\n") {
+		fmt.Printf("  %s\n", line)
+	}
+}
+
 func (c ConsoleTestLogger) TestFinished(id TestID, failed bool, debugOutput framework.CapturedOutput) {
 	if failed {
 		_, _ = consoleTestFailedColor.Printf("  FAILED: %s\n", id)
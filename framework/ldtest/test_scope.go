@@ -23,6 +23,13 @@ type T struct {
 	skipReason  string
 	cleanups    []func()
 	errors      []error
+	reports     []string
+
+	// Once the result has been recorded, resultIndex and failureIndex (or -1 if the test did not
+	// fail) locate it, so that reports added by cleanup functions can still be included.
+	recorded     bool
+	resultIndex  int
+	failureIndex int
 }
 
 // TestConfiguration contains options for the entire test run.
@@ -76,10 +83,12 @@ func (t *T) run(action func(*T)) {
 				t.env.config.TestLogger.TestError(t.id, addError)
 			}
 		}
-		result := TestResult{TestID: t.id, Errors: t.errors}
+		result := TestResult{TestID: t.id, Errors: t.errors, Reports: t.reports}
 		t.env.results.Tests = append(t.env.results.Tests, result)
+		t.recorded, t.resultIndex, t.failureIndex = true, len(t.env.results.Tests)-1, -1
 		if t.failed {
 			t.env.results.Failures = append(t.env.results.Failures, result)
+			t.failureIndex = len(t.env.results.Failures) - 1
 		}
 		for i := len(t.cleanups) - 1; i >= 0; i-- {
 			t.cleanups[i]()
@@ -149,6 +158,23 @@ func (t *T) SkipWithReason(reason string) {
 	t.Skip()
 }
 
+// Report adds a message, such as a performance measurement, to the results for this test scope.
+// Unlike debug output, reports are shown whether or not the test fails, if the TestLogger
+// implements TestReportLogger. It can be called from a cleanup function.
+func (t *T) Report(message string, args ...interface{}) {
+	report := fmt.Sprintf(message, args...)
+	t.reports = append(t.reports, report)
+	if t.recorded { // we are in a cleanup function
+		t.env.results.Tests[t.resultIndex].Reports = t.reports
+		if t.failureIndex >= 0 {
+			t.env.results.Failures[t.failureIndex].Reports = t.reports
+		}
+	}
+	if reportLogger, ok := t.env.config.TestLogger.(TestReportLogger); ok {
+		reportLogger.TestReport(t.id, report)
+	}
+}
+
 // Debug writes a message to the output for this test scope.
 func (t *T) Debug(message string, args ...interface{}) {
 	t.debugLogger.Printf(message, args...)
@@ -171,3 +171,38 @@ func TestTestScopeFilter(t *testing.T) {
 	assert.Equal(t, TestID{"b"}, result.Tests[2].TestID)
 	assert.Equal(t, TestID(nil), result.Tests[3].TestID)
 }
+
+type recordingReportLogger struct {
+	nullTestLogger
+	reports []string
+}
+
+func (l *recordingReportLogger) TestReport(id TestID, report string) {
+	l.reports = append(l.reports, id.String()+": "+report)
+}
+
+func TestTestScopeReports(t *testing.T) {
+	logger := &recordingReportLogger{}
+	result := Run(TestConfiguration{TestLogger: logger}, func(ldt *T) {
+		ldt.Run("passes", func(ldt1 *T) {
+			ldt1.Report("measured %d", 1)
+		})
+		ldt.Run("fails", func(ldt2 *T) {
+			ldt2.Report("measured %d", 2)
+			ldt2.Errorf("failed")
+		})
+		ldt.Run("reports in cleanup", func(ldt3 *T) {
+			ldt3.Report("measured %d", 3)
+			ldt3.Defer(func() { ldt3.Report("measured %d", 4) })
+			ldt3.Errorf("failed")
+		})
+	})
+
+	assert.Equal(t, []string{"passes: measured 1", "fails: measured 2", "reports in cleanup: measured 3",
+		"reports in cleanup: measured 4"}, logger.reports)
+	assert.Equal(t, []string{"measured 1"}, result.Tests[0].Reports)
+	assert.Equal(t, []string{"measured 2"}, result.Tests[1].Reports)
+	assert.Equal(t, []string{"measured 3", "measured 4"}, result.Tests[2].Reports)
+	assert.Equal(t, []string{"measured 3", "measured 4"}, result.Failures[1].Reports)
+	assert.Nil(t, result.Tests[3].Reports)
+}
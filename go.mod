@@ -8,4 +8,5 @@ require (
 	github.com/launchdarkly/go-test-helpers/v2 v2.2.0
 	github.com/stretchr/testify v1.6.1
 	gopkg.in/launchdarkly/go-sdk-common.v2 v2.4.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -10,13 +10,26 @@ import (
 	"github.com/launchdarkly/sse-contract-tests/framework"
 	"github.com/launchdarkly/sse-contract-tests/framework/harness"
 	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/sseservertests"
 	"github.com/launchdarkly/sse-contract-tests/ssetests"
 )
 
 const defaultPort = 8111
 const statusQueryTimeout = time.Second * 10
 
+const (
+	suiteClient = "client"
+	suiteServer = "server"
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == captureCommandName {
+		if !runCaptureCommand(os.Args[2:]) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	fmt.Print("sse-contract-tests 2.31.0") // x-release-please-version
 
 	var params commandParams
@@ -29,7 +42,7 @@ func main() {
 		mainDebugLogger = log.New(os.Stdout, "", log.LstdFlags)
 	}
 
-	harness, err := harness.NewTestHarness(
+	testHarness, err := harness.NewTestHarness(
 		params.serviceURL,
 		params.host,
 		params.port,
@@ -42,8 +55,44 @@ func main() {
 		os.Exit(1)
 	}
 
+	allCapabilities, runTestSuite := ssetests.AllCapabilities, ssetests.RunTestSuite
+	if params.suite == suiteServer {
+		allCapabilities, runTestSuite = sseservertests.AllCapabilities, sseservertests.RunTestSuite
+	}
+
 	fmt.Println()
-	ldtest.PrintFilterDescription(params.filters, ssetests.AllCapabilities, harness.TestServiceInfo().Capabilities)
+	ldtest.PrintFilterDescription(params.filters, allCapabilities, testHarness.TestServiceInfo().Capabilities)
+
+	var replays []ssetests.Replay
+	if params.replayDir != "" {
+		if replays, err = ssetests.LoadReplays(params.replayDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load stream captures: %s\n", err)
+			os.Exit(1)
+		}
+		runTestSuite = func(h *harness.TestHarness, filter ldtest.Filter, logger ldtest.TestLogger) ldtest.Results {
+			return ssetests.RunReplaySuite(h, replays, filter, logger)
+		}
+	}
+
+	if params.scenarioDir != "" {
+		files, err := ssetests.LoadScenarioFiles(os.DirFS(params.scenarioDir))
+		if err == nil && len(files) == 0 {
+			err = fmt.Errorf("no scenario files were found in %s", params.scenarioDir)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Could not load scenarios: %s\n", err)
+			os.Exit(1)
+		}
+		runTestSuite = func(h *harness.TestHarness, filter ldtest.Filter, logger ldtest.TestLogger) ldtest.Results {
+			return ssetests.RunScenarioSuite(h, files, filter, logger)
+		}
+	}
+
+	if params.stress {
+		runTestSuite = func(h *harness.TestHarness, filter ldtest.Filter, logger ldtest.TestLogger) ldtest.Results {
+			return ssetests.RunStressSuite(h, params.stressOptions, filter, logger)
+		}
+	}
 
 	fmt.Println("Running test suite")
 
@@ -52,14 +101,14 @@ func main() {
 		DebugOutputOnSuccess: params.debugAll,
 	}
 
-	results := ssetests.RunTestSuite(harness, params.filters.Match, testLogger)
+	results := runTestSuite(testHarness, params.filters.Match, testLogger)
 
 	fmt.Println()
 	ldtest.PrintResults(results)
 
 	if params.stopServiceAtEnd {
 		fmt.Println("Stopping test service")
-		if err := harness.StopService(); err != nil {
+		if err := testHarness.StopService(); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to stop test service: %s\n", err)
 		}
 	}
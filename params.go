@@ -6,10 +6,16 @@ import (
 	"os"
 
 	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/ssetests"
 )
 
 type commandParams struct {
 	serviceURL       string
+	suite            string
+	replayDir        string
+	scenarioDir      string
+	stress           bool
+	stressOptions    ssetests.StressOptions
 	port             int
 	host             string
 	filters          ldtest.RegexFilters
@@ -21,6 +27,14 @@ type commandParams struct {
 func (c *commandParams) Read(args []string) bool {
 	fs := flag.NewFlagSet("", flag.ExitOnError)
 	fs.StringVar(&c.serviceURL, "url", "", "test service URL")
+	fs.StringVar(&c.suite, "suite", suiteClient, `test suite to run: "client" or "server"`)
+	fs.StringVar(&c.replayDir, "replay", "", "directory of stream captures to replay, instead of running the test suite")
+	fs.StringVar(&c.scenarioDir, "scenarios", "", "directory of scenario files to run, instead of running the test suite")
+	fs.BoolVar(&c.stress, "stress", false, "run stress tests that measure throughput, instead of the test suite")
+	fs.IntVar(&c.stressOptions.EventCount, "stress-events", ssetests.DefaultStressEventCount,
+		"number of events to send in each stress test")
+	fs.IntVar(&c.stressOptions.EventSize, "stress-event-size", ssetests.DefaultStressEventSize,
+		"size of the data in each stress test event, in bytes")
 	fs.StringVar(&c.host, "host", "localhost", "external hostname of the test harness")
 	fs.IntVar(&c.port, "port", defaultPort, "port that the test harness will listen on")
 	fs.Var(&c.filters.MustMatch, "run", "regex pattern(s) to select tests to run")
@@ -39,5 +53,31 @@ func (c *commandParams) Read(args []string) bool {
 		fs.Usage()
 		return false
 	}
+	if c.suite != suiteClient && c.suite != suiteServer {
+		fmt.Fprintf(os.Stderr, "-suite must be %q or %q\n", suiteClient, suiteServer)
+		fs.Usage()
+		return false
+	}
+	modes := 0
+	for _, selected := range []bool{c.replayDir != "", c.scenarioDir != "", c.stress} {
+		if selected {
+			modes++
+		}
+	}
+	if modes > 1 {
+		fmt.Fprintln(os.Stderr, "only one of -replay, -scenarios, and -stress can be used")
+		fs.Usage()
+		return false
+	}
+	if modes > 0 && c.suite != suiteClient {
+		fmt.Fprintln(os.Stderr, "-replay, -scenarios, and -stress can only be used with the client test suite")
+		fs.Usage()
+		return false
+	}
+	if c.stressOptions.EventCount <= 0 || c.stressOptions.EventSize <= 0 {
+		fmt.Fprintln(os.Stderr, "-stress-events and -stress-event-size must be positive")
+		fs.Usage()
+		return false
+	}
 	return true
 }
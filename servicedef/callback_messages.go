@@ -0,0 +1,63 @@
+package servicedef
+
+const (
+	CallbackKindEvent   = "event"
+	CallbackKindComment = "comment"
+	CallbackKindError   = "error"
+
+	// CallbackKindOpened and CallbackKindClosed report changes in the connection state, if the
+	// stream was created with CreateStreamParams.ReportConnectionState.
+	CallbackKindOpened = "opened"
+	CallbackKindClosed = "closed"
+)
+
+// Values for CallbackMessage.ErrorCategory, for a test service with the "error-categories"
+// capability.
+const (
+	// ErrorCategoryHTTPStatus means the server returned an HTTP error status.
+	ErrorCategoryHTTPStatus = "http_status"
+
+	// ErrorCategoryIO means the connection failed or was closed.
+	ErrorCategoryIO = "io"
+
+	// ErrorCategoryTimeout means the client gave up waiting for data.
+	ErrorCategoryTimeout = "timeout"
+
+	// ErrorCategoryInvalidContentType means the response was not text/event-stream.
+	ErrorCategoryInvalidContentType = "invalid_content_type"
+
+	// ErrorCategoryRedirect means the server returned a redirect that the client could not follow.
+	ErrorCategoryRedirect = "redirect"
+)
+
+// CallbackMessage is the JSON object that the test service sends to the callback endpoint
+// whenever its SSE client has something to report. A test service with the "callback-batch"
+// capability can also send a JSON array of these, as described in docs/service_spec.md.
+type CallbackMessage struct {
+	Kind    string         `json:"kind"`
+	Event   *CallbackEvent `json:"event,omitempty"`
+	Comment string         `json:"comment,omitempty"`
+	Error   string         `json:"error,omitempty"`
+
+	// These properties describe an error in more detail, if the test service has the
+	// "error-categories" capability.
+	ErrorCategory string `json:"errorCategory,omitempty"`
+	HTTPStatus    int    `json:"httpStatus,omitempty"`
+	WillRetry     *bool  `json:"willRetry,omitempty"`
+}
+
+// PolledMessage is an item in the JSON array that the test service returns when the test harness
+// polls for messages, if the stream was created with CallbackTransportPoll.
+type PolledMessage struct {
+	Counter int             `json:"counter"`
+	Message CallbackMessage `json:"message"`
+}
+
+// CallbackEvent contains the fields of an SSE event, as part of a CallbackMessage whose Kind
+// is CallbackKindEvent.
+type CallbackEvent struct {
+	Type       string `json:"type"`
+	Data       string `json:"data"`
+	ID         string `json:"id"`
+	DataBase64 string `json:"dataBase64,omitempty"`
+}
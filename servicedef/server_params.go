@@ -0,0 +1,45 @@
+package servicedef
+
+import "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+const (
+	ServerCommandSendEvent        = "sendEvent"
+	ServerCommandSendComment      = "sendComment"
+	ServerCommandSendRetry        = "sendRetry"
+	ServerCommandCloseConnections = "closeConnections"
+)
+
+// CreateServerParams is the request body for creating an SSE server instance in a test service
+// for the server-side test suite.
+type CreateServerParams struct {
+	Tag         string              `json:"tag"`
+	HeartbeatMS ldvalue.OptionalInt `json:"heartbeatMs,omitempty"`
+}
+
+// CreateServerResponse is the response body that the test service returns after creating an SSE
+// server instance.
+type CreateServerResponse struct {
+	StreamURL string `json:"streamUrl"`
+}
+
+// ServerCommandParams is the request body for a command sent to an SSE server instance.
+type ServerCommandParams struct {
+	Command string               `json:"command"`
+	Event   *ServerEventParams   `json:"event,omitempty"`
+	Comment *ServerCommentParams `json:"comment,omitempty"`
+	Retry   *ServerRetryParams   `json:"retry,omitempty"`
+}
+
+type ServerEventParams struct {
+	Type string `json:"type,omitempty"`
+	Data string `json:"data"`
+	ID   string `json:"id,omitempty"`
+}
+
+type ServerCommentParams struct {
+	Text string `json:"text"`
+}
+
+type ServerRetryParams struct {
+	RetryMS int `json:"retryMs"`
+}
@@ -2,25 +2,71 @@ package servicedef
 
 import "gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
 
-const CommandRestart = "restart"
+const (
+	CommandListen     = "listen"
+	CommandRestart    = "restart"
+	CommandSetHeaders = "setHeaders"
+	CommandSetURL     = "setUrl"
+)
+
+const (
+	// CallbackTransportStream means that the test service should send all of its callback messages
+	// for a stream over a single long-lived request, as described in docs/service_spec.md. If
+	// CreateStreamParams.CallbackTransport is empty, each message is sent in its own request.
+	CallbackTransportStream = "stream"
+
+	// CallbackStreamPath is the subpath of the callback URL for CallbackTransportStream.
+	CallbackStreamPath = "/stream"
+
+	// CallbackTransportPoll means that the test service should not send callback messages at all,
+	// but should keep them until the test harness polls for them at PolledMessagesPath.
+	CallbackTransportPoll = "poll"
+
+	// PolledMessagesPath is the subpath of a stream's resource URL for CallbackTransportPoll. The
+	// test harness adds a query parameter "after" whose value is the highest counter it has seen.
+	PolledMessagesPath = "/messages"
+)
+
+// StatusResponse is the JSON object that the test service returns from its status resource.
+type StatusResponse struct {
+	Name         string   `json:"name,omitempty"`
+	Capabilities []string `json:"capabilities"`
+}
 
 type CreateStreamParams struct {
-	Tag            string              `json:"tag"`
-	CallbackURL    string              `json:"callbackUrl"`
-	StreamURL      string              `json:"streamUrl"`
-	InitialDelayMS ldvalue.OptionalInt `json:"initialDelayMs,omitempty"`
-	LastEventID    string              `json:"lastEventId,omitempty"`
-	Method         string              `json:"method,omitempty"`
-	Body           string              `json:"body,omitempty"`
-	Headers        map[string]string   `json:"headers,omitempty"`
-	ReadTimeoutMS  ldvalue.OptionalInt `json:"readTimeoutMs,omitempty"`
+	Tag                   string              `json:"tag"`
+	CallbackURL           string              `json:"callbackUrl"`
+	StreamURL             string              `json:"streamUrl"`
+	InitialDelayMS        ldvalue.OptionalInt `json:"initialDelayMs,omitempty"`
+	LastEventID           string              `json:"lastEventId,omitempty"`
+	Method                string              `json:"method,omitempty"`
+	Body                  string              `json:"body,omitempty"`
+	Headers               map[string]string   `json:"headers,omitempty"`
+	ReadTimeoutMS         ldvalue.OptionalInt `json:"readTimeoutMs,omitempty"`
+	CallbackTransport     string              `json:"callbackTransport,omitempty"`
+	ReportConnectionState bool                `json:"reportConnectionState,omitempty"`
+	ProxyURL              string              `json:"proxyUrl,omitempty"`
 }
 
 type CommandParams struct {
-	Command string        `json:"command"`
-	Listen  *ListenParams `json:"listen"`
+	Command    string            `json:"command"`
+	Listen     *ListenParams     `json:"listen"`
+	SetHeaders *SetHeadersParams `json:"setHeaders,omitempty"`
+	SetURL     *SetURLParams     `json:"setUrl,omitempty"`
 }
 
 type ListenParams struct {
 	Type string `json:"type"`
 }
+
+// SetHeadersParams is the parameter object for CommandSetHeaders. Headers replaces the custom
+// headers that were set in CreateStreamParams, starting with the client's next request.
+type SetHeadersParams struct {
+	Headers map[string]string `json:"headers"`
+}
+
+// SetURLParams is the parameter object for CommandSetURL. StreamURL replaces the stream URL that
+// was set in CreateStreamParams, starting with the client's next request.
+type SetURLParams struct {
+	StreamURL string `json:"streamUrl"`
+}
@@ -0,0 +1,7 @@
+// Package sseservertests contains the contract tests for SSE server implementations, and their
+// supporting API.
+//
+// In these tests the roles are the reverse of the ones in the ssetests package: the test service
+// runs an SSE server, and the test harness connects to it as a strict SSE client, verifying
+// everything that the server sends.
+package sseservertests
@@ -0,0 +1,25 @@
+package sseservertests
+
+import (
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+)
+
+type SSEServerTestContext struct {
+	harness *harness.TestHarness
+}
+
+func requireContext(t *ldtest.T) SSEServerTestContext {
+	if c, ok := t.Context().(SSEServerTestContext); ok {
+		return c
+	}
+	panic("SSEServerTestContext was not included in the global test configuration!" +
+		" This is a basic mistake in the initialization logic.")
+}
+
+// NewServerAndConnection starts an SSE server in the test service and connects to it.
+func NewServerAndConnection(t *ldtest.T, params ...SSEServerConfigurer) (*SSEServer, *StreamReader) {
+	server := NewSSEServer(t, params...)
+	reader := server.Connect(t)
+	return server, reader
+}
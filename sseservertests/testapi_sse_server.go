@@ -0,0 +1,98 @@
+package sseservertests
+
+import (
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SSEServer represents an SSE server instance that we have asked the test service to create.
+type SSEServer struct {
+	service   *harness.TestServiceEntity
+	streamURL string
+	logger    framework.Logger
+}
+
+type SSEServerConfigurer interface {
+	ApplyConfiguration(*servicedef.CreateServerParams)
+}
+
+type serverParamsConfigurer servicedef.CreateServerParams
+
+func NewSSEServer(t *ldtest.T, configurers ...SSEServerConfigurer) *SSEServer {
+	testHarness := requireContext(t).harness
+
+	params := servicedef.CreateServerParams{}
+	for _, conf := range configurers {
+		conf.ApplyConfiguration(&params)
+	}
+	params.Tag = t.ID().String()
+
+	var resp servicedef.CreateServerResponse
+	service, err := testHarness.NewTestServiceEntityWithResponse(params, "SSE server", t.DebugLogger(), &resp)
+	require.NoError(t, err)
+	t.Defer(func() {
+		_ = service.Close()
+	})
+	require.NotEmpty(t, resp.StreamURL, "test service did not return a stream URL")
+
+	return &SSEServer{service: service, streamURL: resp.StreamURL, logger: t.DebugLogger()}
+}
+
+// StreamURL returns the URL of the SSE endpoint that the test service created.
+func (s *SSEServer) StreamURL() string {
+	return s.streamURL
+}
+
+// Connect makes a new request to the SSE endpoint and waits for a successful response.
+func (s *SSEServer) Connect(t *ldtest.T, options ...ConnectOption) *StreamReader {
+	r, err := s.ConnectWithOptions(t, options...)
+	require.NoError(t, err)
+	return r
+}
+
+// SendEvent tells the SSE server to send an event to all connected clients.
+func (s *SSEServer) SendEvent(t *ldtest.T, event servicedef.ServerEventParams) {
+	s.sendCommand(t, servicedef.ServerCommandParams{
+		Command: servicedef.ServerCommandSendEvent,
+		Event:   &event,
+	})
+}
+
+// SendComment tells the SSE server to send a comment to all connected clients.
+func (s *SSEServer) SendComment(t *ldtest.T, text string) {
+	t.RequireCapability("comments")
+	s.sendCommand(t, servicedef.ServerCommandParams{
+		Command: servicedef.ServerCommandSendComment,
+		Comment: &servicedef.ServerCommentParams{Text: text},
+	})
+}
+
+// SendRetry tells the SSE server to send a "retry:" field to all connected clients.
+func (s *SSEServer) SendRetry(t *ldtest.T, retryMS int) {
+	t.RequireCapability("retry")
+	s.sendCommand(t, servicedef.ServerCommandParams{
+		Command: servicedef.ServerCommandSendRetry,
+		Retry:   &servicedef.ServerRetryParams{RetryMS: retryMS},
+	})
+}
+
+// CloseConnections tells the SSE server to close all of its active connections.
+func (s *SSEServer) CloseConnections(t *ldtest.T) {
+	s.sendCommand(t, servicedef.ServerCommandParams{Command: servicedef.ServerCommandCloseConnections})
+}
+
+func (s *SSEServer) sendCommand(t *ldtest.T, params servicedef.ServerCommandParams) {
+	require.NoError(t, s.service.SendCommandWithParams(params, s.logger, nil))
+}
+
+func (c serverParamsConfigurer) ApplyConfiguration(p *servicedef.CreateServerParams) {
+	*p = servicedef.CreateServerParams(c)
+}
+
+func WithServerParams(params servicedef.CreateServerParams) SSEServerConfigurer {
+	return serverParamsConfigurer(params)
+}
@@ -0,0 +1,363 @@
+package sseservertests
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	connectTimeout   = time.Second * 5
+	awaitItemTimeout = time.Second * 5
+	readBufferSize   = 8192
+)
+
+// streamHTTPClient is used for every stream request. Its transport does not ask for compression,
+// since Go's default transport would add "Accept-Encoding: gzip" to the request by itself and then
+// quietly decompress the response, hiding what the server really sent.
+var streamHTTPClient = newStreamHTTPClient() //nolint:gochecknoglobals
+
+func newStreamHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DisableCompression = true
+	return &http.Client{Transport: transport}
+}
+
+// StreamReader is a strict SSE client that the test harness uses to read a stream from the test
+// service. Besides parsing events, it records anything in the stream that violates the SSE syntax
+// or that a client could not interpret unambiguously, and every Require method fails the test if
+// there have been any such violations.
+type StreamReader struct {
+	// Response is the HTTP response from the test service. Its body is owned by the StreamReader.
+	Response *http.Response
+
+	itemsCh    chan streamItem
+	cancel     context.CancelFunc
+	violations []string
+	reported   int
+	logger     framework.Logger
+	lock       sync.Mutex
+}
+
+// ReceivedEvent is an SSE event as parsed by the StreamReader. Type is "message" if the server did
+// not specify a type.
+type ReceivedEvent struct {
+	Type string
+	Data string
+	ID   string
+}
+
+func (e ReceivedEvent) String() string {
+	return fmt.Sprintf("{type: %q, data: %q, id: %q}", e.Type, e.Data, e.ID)
+}
+
+type streamItem struct {
+	event   *ReceivedEvent
+	comment *string
+	retry   *int
+	err     error // the stream ended; io.EOF if it ended normally
+}
+
+func (i streamItem) String() string {
+	switch {
+	case i.event != nil:
+		return "event " + i.event.String()
+	case i.comment != nil:
+		return fmt.Sprintf("comment %q", *i.comment)
+	case i.retry != nil:
+		return fmt.Sprintf("retry %d", *i.retry)
+	default:
+		return fmt.Sprintf("end of stream (%s)", i.err)
+	}
+}
+
+// ConnectOption is an optional parameter for SSEServer.Connect.
+type ConnectOption func(*http.Request)
+
+// WithLastEventID sets the Last-Event-Id header in the request.
+func WithLastEventID(id string) ConnectOption {
+	return func(r *http.Request) {
+		r.Header.Set("Last-Event-Id", id)
+	}
+}
+
+// ConnectWithOptions is the same as Connect, but returns an error instead of failing the test.
+func (s *SSEServer) ConnectWithOptions(t *ldtest.T, options ...ConnectOption) (*StreamReader, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, "GET", s.streamURL, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	for _, o := range options {
+		o(req)
+	}
+
+	s.logger.Printf("Connecting to %s", s.streamURL)
+	connectTimer := time.AfterFunc(connectTimeout, cancel)
+	resp, err := streamHTTPClient.Do(req)
+	connectTimer.Stop()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("stream request failed: %w", err)
+	}
+	if resp.StatusCode != 200 {
+		_ = resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("stream request returned HTTP status %d", resp.StatusCode)
+	}
+	r := &StreamReader{
+		Response: resp,
+		itemsCh:  make(chan streamItem, 1000),
+		cancel:   cancel,
+		logger:   t.DebugLogger(),
+	}
+	t.Defer(r.Close)
+	go r.readStream()
+	return r, nil
+}
+
+// Close disconnects from the stream.
+func (r *StreamReader) Close() {
+	r.cancel()
+}
+
+// RequireEvent waits for the next event from the stream, skipping any comments.
+//
+// The test fails and immediately exits if it times out, if the stream ends, or if the stream
+// contains anything other than an event or a comment.
+func (r *StreamReader) RequireEvent(t *ldtest.T) ReceivedEvent {
+	for {
+		item := r.requireItem(t)
+		if item.comment != nil {
+			continue
+		}
+		if item.event == nil {
+			require.Fail(t, "expected an event", "got %s", item)
+		}
+		return *item.event
+	}
+}
+
+// RequireSpecificEvents waits for a series of events, which must match the specified events.
+// An empty Type in an expected event means "message".
+func (r *StreamReader) RequireSpecificEvents(t *ldtest.T, events ...ReceivedEvent) {
+	for _, expected := range events {
+		if expected.Type == "" {
+			expected.Type = "message"
+		}
+		assert.Equal(t, expected, r.RequireEvent(t))
+	}
+}
+
+// RequireComment waits for the next item from the stream, which must be a comment. Since the SSE
+// specification does not say how to interpret a comment, a single leading space in the comment
+// text is ignored, so ": hello" and ":hello" are equivalent.
+func (r *StreamReader) RequireComment(t *ldtest.T) string {
+	item := r.requireItem(t)
+	if item.comment == nil {
+		require.Fail(t, "expected a comment", "got %s", item)
+	}
+	return strings.TrimPrefix(*item.comment, " ")
+}
+
+// RequireRetry waits for the next item from the stream, which must be a "retry:" field.
+func (r *StreamReader) RequireRetry(t *ldtest.T) int {
+	item := r.requireItem(t)
+	if item.retry == nil {
+		require.Fail(t, "expected a retry field", "got %s", item)
+	}
+	return *item.retry
+}
+
+// RequireEnd waits for the server to close the stream, ignoring any comments.
+func (r *StreamReader) RequireEnd(t *ldtest.T) {
+	for {
+		item := r.requireItem(t)
+		if item.comment != nil {
+			continue
+		}
+		if item.err == nil {
+			require.Fail(t, "expected the stream to be closed", "got %s", item)
+		}
+		return
+	}
+}
+
+func (r *StreamReader) requireItem(t *ldtest.T) streamItem {
+	deadline := time.NewTimer(awaitItemTimeout)
+	defer deadline.Stop()
+	select {
+	case item, ok := <-r.itemsCh:
+		r.requireNoViolations(t)
+		if !ok {
+			require.Fail(t, "stream has already ended")
+		}
+		return item
+	case <-deadline.C:
+		r.requireNoViolations(t)
+		require.Fail(t, "timed out waiting for data from stream")
+		return streamItem{}
+	}
+}
+
+func (r *StreamReader) requireNoViolations(t *ldtest.T) {
+	r.lock.Lock()
+	newViolations := r.violations[r.reported:]
+	r.reported = len(r.violations)
+	r.lock.Unlock()
+	for _, v := range newViolations {
+		t.Errorf("stream was not valid SSE: %s", v)
+	}
+	if len(newViolations) != 0 {
+		t.FailNow()
+	}
+}
+
+func (r *StreamReader) addViolation(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	r.logger.Printf("Violation: %s", message)
+	r.lock.Lock()
+	r.violations = append(r.violations, message)
+	r.lock.Unlock()
+}
+
+func (r *StreamReader) readStream() {
+	defer close(r.itemsCh)
+	defer func() { _ = r.Response.Body.Close() }()
+
+	p := strictParser{reader: r}
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := r.Response.Body.Read(buf)
+		if n > 0 {
+			r.logger.Printf(">> received: %q", buf[:n])
+			p.feed(buf[:n])
+		}
+		if err != nil {
+			if p.line.Len() != 0 || p.hasData {
+				r.logger.Printf("Stream ended with an incomplete event, which will be discarded")
+			}
+			r.itemsCh <- streamItem{err: err}
+			return
+		}
+	}
+}
+
+// strictParser parses the stream according to the SSE specification, and reports syntax that a
+// client would have to ignore or could misinterpret.
+type strictParser struct {
+	reader    *StreamReader
+	line      bytes.Buffer
+	lastWasCR bool
+	started   bool
+	data      strings.Builder
+	hasData   bool
+	eventType string
+	lastID    string
+}
+
+func (p *strictParser) feed(chunk []byte) {
+	if !p.started {
+		p.started = true
+		chunk = bytes.TrimPrefix(chunk, []byte("\xEF\xBB\xBF"))
+	}
+	for len(chunk) > 0 {
+		if p.lastWasCR {
+			p.lastWasCR = false
+			if chunk[0] == '\n' {
+				chunk = chunk[1:]
+				continue
+			}
+		}
+		pos := bytes.IndexAny(chunk, "\r\n")
+		if pos < 0 {
+			p.line.Write(chunk)
+			return
+		}
+		p.line.Write(chunk[:pos])
+		p.lastWasCR = chunk[pos] == '\r'
+		chunk = chunk[pos+1:]
+		line := p.line.String()
+		p.line.Reset()
+		p.processLine(line)
+	}
+}
+
+func (p *strictParser) processLine(line string) {
+	if !utf8.ValidString(line) {
+		p.reader.addViolation("line contained invalid UTF-8: %q", line)
+	}
+	if line == "" {
+		p.dispatch()
+		return
+	}
+	if line[0] == ':' {
+		comment := line[1:]
+		p.reader.itemsCh <- streamItem{comment: &comment}
+		return
+	}
+	name, value := line, ""
+	if colon := strings.IndexByte(line, ':'); colon >= 0 {
+		name, value = line[:colon], strings.TrimPrefix(line[colon+1:], " ")
+	}
+	switch name {
+	case "event":
+		p.eventType = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+		p.hasData = true
+	case "id":
+		if strings.ContainsRune(value, 0) {
+			p.reader.addViolation("id field contained a null character: %q", line)
+		} else {
+			p.lastID = value
+		}
+	case "retry":
+		n, err := strconv.Atoi(value)
+		if err != nil || strings.Trim(value, "0123456789") != "" {
+			p.reader.addViolation("retry field did not contain only digits: %q", line)
+			return
+		}
+		p.reader.itemsCh <- streamItem{retry: &n}
+	default:
+		p.reader.addViolation("unknown field name: %q", line)
+	}
+}
+
+func (p *strictParser) dispatch() {
+	if !p.hasData {
+		if p.eventType != "" {
+			p.reader.addViolation("event of type %q had no data field, so it was not dispatched", p.eventType)
+		}
+		p.eventType = ""
+		return
+	}
+	event := ReceivedEvent{
+		Type: p.eventType,
+		Data: strings.TrimSuffix(p.data.String(), "\n"),
+		ID:   p.lastID,
+	}
+	if event.Type == "" {
+		event.Type = "message"
+	}
+	p.data.Reset()
+	p.hasData = false
+	p.eventType = ""
+	p.reader.itemsCh <- streamItem{event: &event}
+}
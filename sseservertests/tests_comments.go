@@ -0,0 +1,59 @@
+package sseservertests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func DoCommentTests(t *ldtest.T) {
+	t.Run("comment", func(t *ldtest.T) {
+		t.RequireCapability("comments")
+		server, stream := NewServerAndConnection(t)
+		server.SendComment(t, "Hello")
+		assert.Equal(t, "Hello", stream.RequireComment(t))
+	})
+
+	t.Run("comment between events", func(t *ldtest.T) {
+		t.RequireCapability("comments")
+		server, stream := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{Data: "first"})
+		server.SendComment(t, "Hello")
+		server.SendEvent(t, servicedef.ServerEventParams{Data: "second"})
+		stream.RequireSpecificEvents(t, ReceivedEvent{Data: "first"})
+		assert.Equal(t, "Hello", stream.RequireComment(t))
+		stream.RequireSpecificEvents(t, ReceivedEvent{Data: "second"})
+	})
+
+	t.Run("heartbeat comments", func(t *ldtest.T) {
+		t.RequireCapability("heartbeat")
+
+		interval := time.Millisecond * 200
+		params := servicedef.CreateServerParams{HeartbeatMS: ldvalue.NewOptionalInt(int(interval / time.Millisecond))}
+		_, stream := NewServerAndConnection(t, WithServerParams(params))
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			_ = stream.RequireComment(t)
+		}
+		// Allow plenty of leeway for timing imprecision; we only want to know that the server is sending
+		// heartbeats at roughly the right rate, rather than only occasionally.
+		assert.Less(t, int64(time.Since(start)), int64(interval*6), "heartbeats were not sent at the requested interval")
+	})
+
+	t.Run("events are delivered normally between heartbeats", func(t *ldtest.T) {
+		t.RequireCapability("heartbeat")
+
+		params := servicedef.CreateServerParams{HeartbeatMS: ldvalue.NewOptionalInt(100)}
+		server, stream := NewServerAndConnection(t, WithServerParams(params))
+
+		time.Sleep(time.Millisecond * 250)
+		server.SendEvent(t, servicedef.ServerEventParams{Data: "Hello"})
+		stream.RequireSpecificEvents(t, ReceivedEvent{Data: "Hello"})
+	})
+}
@@ -0,0 +1,76 @@
+package sseservertests
+
+import (
+	"fmt"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+// DoEventEncodingTests verifies that the server encodes each event so that a client following the
+// SSE specification will see exactly the values that the server was asked to send. The test
+// service is told what to send in abstract terms (type, data, ID); it is up to the SSE server
+// implementation to produce the correct field syntax.
+func DoEventEncodingTests(t *ldtest.T) {
+	testData := func(data string) func(t *ldtest.T) {
+		return func(t *ldtest.T) {
+			server, stream := NewServerAndConnection(t)
+			server.SendEvent(t, servicedef.ServerEventParams{Data: data})
+			stream.RequireSpecificEvents(t, ReceivedEvent{Data: data})
+		}
+	}
+
+	t.Run("one-line data", testData("Hello"))
+
+	// Each line of data must be sent as a separate "data:" field.
+	t.Run("multi-line data", testData("line1\nline2\nline3"))
+
+	t.Run("multi-line data with empty line in middle", testData("line1\n\nline3"))
+
+	t.Run("multi-line data with empty line at end", testData("line1\n"))
+
+	t.Run("multi-line data with empty line at beginning", testData("\nline2"))
+
+	t.Run("empty data", testData(""))
+
+	// The client strips one leading space from a field value, so the server must make sure a
+	// leading space in the data is not lost.
+	t.Run("data with leading space", testData(" Hello"))
+
+	t.Run("data containing colons", testData("a: b: c"))
+
+	t.Run("multi-byte characters", testData("€豆腐"))
+
+	t.Run("event with type", func(t *ldtest.T) {
+		server, stream := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{Type: "greeting", Data: "Hello"})
+		stream.RequireSpecificEvents(t, ReceivedEvent{Type: "greeting", Data: "Hello"})
+	})
+
+	t.Run("event with ID", func(t *ldtest.T) {
+		server, stream := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "abc", Data: "Hello"})
+		stream.RequireSpecificEvents(t, ReceivedEvent{ID: "abc", Data: "Hello"})
+	})
+
+	t.Run("event type does not carry over to next event", func(t *ldtest.T) {
+		server, stream := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{Type: "greeting", Data: "Hello"})
+		server.SendEvent(t, servicedef.ServerEventParams{Data: "World"})
+		stream.RequireSpecificEvents(t,
+			ReceivedEvent{Type: "greeting", Data: "Hello"},
+			ReceivedEvent{Data: "World"},
+		)
+	})
+
+	t.Run("many events in order", func(t *ldtest.T) {
+		server, stream := NewServerAndConnection(t)
+		var expected []ReceivedEvent
+		for i := 0; i < 50; i++ {
+			data := fmt.Sprintf("message %d", i)
+			server.SendEvent(t, servicedef.ServerEventParams{Data: data})
+			expected = append(expected, ReceivedEvent{Data: data})
+		}
+		stream.RequireSpecificEvents(t, expected...)
+	})
+}
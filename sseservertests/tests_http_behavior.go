@@ -0,0 +1,40 @@
+package sseservertests
+
+import (
+	"mime"
+	"strings"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func DoHTTPBehaviorTests(t *ldtest.T) {
+	t.Run("content type is text/event-stream", func(t *ldtest.T) {
+		_, stream := NewServerAndConnection(t)
+		contentType := stream.Response.Header.Get("Content-Type")
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		assert.NoError(t, err, "invalid Content-Type header %q", contentType)
+		assert.Equal(t, "text/event-stream", mediaType, "incorrect Content-Type header")
+		if charset, ok := params["charset"]; ok {
+			// SSE streams are always UTF-8, so any other charset would be misleading.
+			assert.Equal(t, "utf-8", strings.ToLower(charset), "Content-Type header specified a charset other than UTF-8")
+		}
+	})
+
+	t.Run("response disables caching", func(t *ldtest.T) {
+		_, stream := NewServerAndConnection(t)
+		cacheControl := strings.ToLower(strings.Join(stream.Response.Header.Values("Cache-Control"), ","))
+		assert.True(t, strings.Contains(cacheControl, "no-cache") || strings.Contains(cacheControl, "no-store"),
+			"Cache-Control header should include no-cache or no-store, but was %q", cacheControl)
+	})
+
+	t.Run("response is not compressed unless requested", func(t *ldtest.T) {
+		_, stream := NewServerAndConnection(t)
+		assert.Empty(t, stream.Response.Header.Get("Content-Encoding"),
+			"response had a Content-Encoding even though the request had no Accept-Encoding")
+		// The test harness's HTTP client does not decompress anything, but it would be easy to lose that
+		// setting, so we make sure that this test would not be fooled by transparent decompression.
+		assert.False(t, stream.Response.Uncompressed, "response was compressed and was decompressed by the HTTP client")
+	})
+}
@@ -0,0 +1,74 @@
+package sseservertests
+
+import (
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func DoReconnectionTests(t *ldtest.T) {
+	t.Run("retry field", func(t *ldtest.T) {
+		t.RequireCapability("retry")
+		server, stream := NewServerAndConnection(t)
+		server.SendRetry(t, 3000)
+		assert.Equal(t, 3000, stream.RequireRetry(t))
+	})
+
+	t.Run("server can close connection", func(t *ldtest.T) {
+		server, stream := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{Data: "Hello"})
+		stream.RequireSpecificEvents(t, ReceivedEvent{Data: "Hello"})
+		server.CloseConnections(t)
+		stream.RequireEnd(t)
+	})
+
+	t.Run("new connection receives only new events if Last-Event-Id is not sent", func(t *ldtest.T) {
+		server, stream1 := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "1", Data: "first"})
+		stream1.RequireSpecificEvents(t, ReceivedEvent{ID: "1", Data: "first"})
+		server.CloseConnections(t)
+		stream1.RequireEnd(t)
+
+		stream2 := server.Connect(t)
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "2", Data: "second"})
+		stream2.RequireSpecificEvents(t, ReceivedEvent{ID: "2", Data: "second"})
+	})
+
+	t.Run("replays events after Last-Event-Id on reconnect", func(t *ldtest.T) {
+		t.RequireCapability("last-event-id")
+
+		server, stream1 := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "1", Data: "first"})
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "2", Data: "second"})
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "3", Data: "third"})
+		stream1.RequireSpecificEvents(t,
+			ReceivedEvent{ID: "1", Data: "first"},
+			ReceivedEvent{ID: "2", Data: "second"},
+			ReceivedEvent{ID: "3", Data: "third"},
+		)
+		server.CloseConnections(t)
+		stream1.RequireEnd(t)
+
+		// Pretend that the client had only seen the first event before the connection was lost.
+		stream2 := server.Connect(t, WithLastEventID("1"))
+		stream2.RequireSpecificEvents(t,
+			ReceivedEvent{ID: "2", Data: "second"},
+			ReceivedEvent{ID: "3", Data: "third"},
+		)
+	})
+
+	t.Run("replays nothing if Last-Event-Id is the latest event", func(t *ldtest.T) {
+		t.RequireCapability("last-event-id")
+
+		server, stream1 := NewServerAndConnection(t)
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "1", Data: "first"})
+		stream1.RequireSpecificEvents(t, ReceivedEvent{ID: "1", Data: "first"})
+		server.CloseConnections(t)
+		stream1.RequireEnd(t)
+
+		stream2 := server.Connect(t, WithLastEventID("1"))
+		server.SendEvent(t, servicedef.ServerEventParams{ID: "2", Data: "second"})
+		stream2.RequireSpecificEvents(t, ReceivedEvent{ID: "2", Data: "second"})
+	})
+}
@@ -0,0 +1,35 @@
+package sseservertests
+
+import (
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+)
+
+var AllCapabilities = []string{ //nolint:gochecknoglobals
+	"comments",
+	"heartbeat",
+	"last-event-id",
+	"retry",
+}
+
+func RunTestSuite(
+	harness *harness.TestHarness,
+	filter ldtest.Filter,
+	testLogger ldtest.TestLogger,
+) ldtest.Results {
+	config := ldtest.TestConfiguration{
+		Filter:       filter,
+		Capabilities: harness.TestServiceInfo().Capabilities,
+		TestLogger:   testLogger,
+		Context: SSEServerTestContext{
+			harness: harness,
+		},
+	}
+
+	return ldtest.Run(config, func(t *ldtest.T) {
+		t.Run("HTTP behavior", DoHTTPBehaviorTests)
+		t.Run("event encoding", DoEventEncodingTests)
+		t.Run("comments", DoCommentTests)
+		t.Run("reconnection", DoReconnectionTests)
+	})
+}
@@ -0,0 +1,182 @@
+package ssetests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+)
+
+const (
+	// CaptureFileSuffix is the file name suffix for a stream capture in a replay directory.
+	CaptureFileSuffix = ".capture.json"
+
+	// ExpectedEventsFileSuffix is the file name suffix for the expected events that go with a
+	// stream capture of the same name.
+	ExpectedEventsFileSuffix = ".events.json"
+
+	captureReadBufferSize = 8192
+)
+
+// StreamCapture is a recording of the raw bytes of an SSE stream, as they were received from a
+// real server. Replaying it feeds the same bytes to the SSE client in the same chunks, with the
+// same delays between them, so that parsing problems that depend on where the chunk boundaries
+// fall can be reproduced.
+type StreamCapture struct {
+	// URL is the URL that the stream was captured from. It is informational only.
+	URL string `json:"url,omitempty"`
+
+	// Chunks are the pieces of the stream in the order that they were received.
+	Chunks []CapturedChunk `json:"chunks"`
+}
+
+// CapturedChunk is a piece of a stream that was received in a single read. In JSON, Data is
+// encoded as base64, since a stream is not guaranteed to be valid UTF-8 and a chunk boundary can
+// fall in the middle of a multi-byte character.
+type CapturedChunk struct {
+	// DelayMS is the number of milliseconds between the previous chunk, or the start of the
+	// response if this is the first chunk, and this one.
+	DelayMS int `json:"delayMs"`
+
+	Data []byte `json:"data"`
+}
+
+// CaptureOptions contains optional parameters for CaptureStream.
+type CaptureOptions struct {
+	// Headers are added to the stream request.
+	Headers http.Header
+
+	// Duration is how long to keep reading the stream. If it is zero, reading continues until the
+	// server closes the stream.
+	Duration time.Duration
+}
+
+// Replay is a stream capture together with the events that an SSE client should produce when it
+// reads the captured stream.
+type Replay struct {
+	Name     string
+	Capture  StreamCapture
+	Expected []EventMessage
+}
+
+// CaptureStream connects to an SSE endpoint and records everything it receives, with the chunk
+// boundaries and timings exactly as they were seen by this client.
+//
+// The chunk boundaries seen by a client are not always the same as the boundaries of the writes
+// made by the server, since the network can split or combine them, but they are what a real
+// client had to deal with.
+func CaptureStream(url string, options CaptureOptions, logger framework.Logger) (StreamCapture, error) {
+	if logger == nil {
+		logger = framework.NullLogger()
+	}
+	ctx := context.Background()
+	if options.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Duration)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return StreamCapture{}, err
+	}
+	for name, values := range options.Headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	// We use our own transport so that Go does not transparently decompress the stream; we want to
+	// capture exactly what the SSE client would have parsed, and we do not ask for compression.
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, DisableCompression: true}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return StreamCapture{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != 200 {
+		return StreamCapture{}, fmt.Errorf("stream request returned HTTP status %d", resp.StatusCode)
+	}
+
+	capture := StreamCapture{URL: url}
+	buf := make([]byte, captureReadBufferSize)
+	lastTime := time.Now()
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			now := time.Now()
+			chunk := CapturedChunk{
+				DelayMS: int(now.Sub(lastTime) / time.Millisecond),
+				Data:    append([]byte(nil), buf[:n]...),
+			}
+			lastTime = now
+			logger.Printf("Captured %d bytes after %dms", n, chunk.DelayMS)
+			capture.Chunks = append(capture.Chunks, chunk)
+		}
+		if err != nil {
+			if err == io.EOF || errors.Is(err, context.DeadlineExceeded) {
+				return capture, nil
+			}
+			return capture, err
+		}
+	}
+}
+
+// WriteFile writes the capture as JSON.
+func (c StreamCapture) WriteFile(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644) //nolint:gosec
+}
+
+// LoadReplays reads every stream capture in a directory, along with its expected events. For a
+// capture called "name.capture.json", the expected events must be in "name.events.json", as a
+// JSON array of objects with the same properties as EventMessage. The replays are sorted by name.
+func LoadReplays(dir string) ([]Replay, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+CaptureFileSuffix))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files ending in %q were found in %s", CaptureFileSuffix, dir)
+	}
+	sort.Strings(paths)
+	var replays []Replay
+	for _, path := range paths {
+		r := Replay{Name: strings.TrimSuffix(filepath.Base(path), CaptureFileSuffix)}
+		if err := readJSONFile(path, &r.Capture); err != nil {
+			return nil, err
+		}
+		eventsPath := filepath.Join(dir, r.Name+ExpectedEventsFileSuffix)
+		if err := readJSONFile(eventsPath, &r.Expected); err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("capture %q has no expected events file; it should be %s", r.Name, eventsPath)
+			}
+			return nil, err
+		}
+		replays = append(replays, r)
+	}
+	return replays, nil
+}
+
+func readJSONFile(path string, out interface{}) error {
+	data, err := ioutil.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s is not valid: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package ssetests
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const scenarioChunkDelay = time.Millisecond * 10
+
+//go:embed scenarios
+var embeddedScenarios embed.FS //nolint:gochecknoglobals
+
+// ScenarioFile is the contents of a declarative test scenario file. See docs/scenarios.md.
+type ScenarioFile struct {
+	// Name is the file name without its extension.
+	Name string `yaml:"-"`
+
+	Scenarios []Scenario `yaml:"scenarios"`
+}
+
+// Scenario is either a single test, if it has Steps, or a group of tests, if it has Scenarios.
+// Requires, Vars, and ChunkSizes are inherited by all of the tests in a group.
+type Scenario struct {
+	Name string `yaml:"name"`
+
+	// Requires is a list of capabilities that the test service must have for this test to run.
+	Requires []string `yaml:"requires"`
+
+	// Vars are substituted for "${NAME}" in the data of every "send" step.
+	Vars map[string]string `yaml:"vars"`
+
+	// ChunkSizes, if not empty, causes the test to be run once for each chunk size, with the data
+	// of every "send" step split into chunks of that many bytes. A chunk size of zero means that
+	// the data is sent in one chunk.
+	ChunkSizes []int `yaml:"chunkSizes"`
+
+	Scenarios []Scenario     `yaml:"scenarios"`
+	Steps     []ScenarioStep `yaml:"steps"`
+}
+
+// ScenarioStep is a single action in a test. Exactly one of its fields must be set.
+type ScenarioStep struct {
+	// Send is data to be sent on the stream.
+	Send *string `yaml:"send"`
+
+	// DelayMS is a number of milliseconds to wait before the next step.
+	DelayMS int `yaml:"delayMs"`
+
+	// Break closes the stream connection. Since SSE clients differ as to whether they report this
+	// as an error, an error reported by the client right after this step is ignored.
+	Break bool `yaml:"break"`
+
+	// AwaitReconnect waits for the client to reconnect; subsequent "send" steps use the new
+	// connection.
+	AwaitReconnect bool `yaml:"awaitReconnect"`
+
+	// ExpectEvent waits for the client to receive an event, which must match this one.
+	ExpectEvent *EventMessage `yaml:"expectEvent"`
+
+	// ExpectComment waits for the client to receive a comment, which must match this one. The step
+	// is skipped if the test service does not have the "comments" capability.
+	ExpectComment *string `yaml:"expectComment"`
+
+	// ExpectError waits for the client to report an error.
+	ExpectError bool `yaml:"expectError"`
+}
+
+// LoadScenarioFiles reads every scenario file in the root directory of a file system. Files can
+// be in YAML (".yaml" or ".yml") or JSON (".json") format. The files are sorted by name.
+func LoadScenarioFiles(fsys fs.FS) ([]ScenarioFile, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var files []ScenarioFile
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		f, err := LoadScenarioFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+// LoadScenarioFile reads and validates a single scenario file.
+func LoadScenarioFile(fsys fs.FS, filePath string) (ScenarioFile, error) {
+	data, err := fs.ReadFile(fsys, filePath)
+	if err != nil {
+		return ScenarioFile{}, err
+	}
+	// Since JSON is a subset of YAML, the YAML parser can read either format.
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var f ScenarioFile
+	if err := decoder.Decode(&f); err != nil && err != io.EOF {
+		return ScenarioFile{}, fmt.Errorf("%s is not valid: %w", filePath, err)
+	}
+	f.Name = strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	for _, s := range f.Scenarios {
+		if err := s.validate(); err != nil {
+			return ScenarioFile{}, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+	return f, nil
+}
+
+// RunScenarioFiles runs each file's scenarios as a subtest with the same name as the file.
+func RunScenarioFiles(t *ldtest.T, files []ScenarioFile) {
+	for _, f := range files {
+		t.Run(f.Name, func(t *ldtest.T) { RunScenarioFile(t, f) })
+	}
+}
+
+// RunScenarioFile runs each of the scenarios in a file as a subtest of the current test.
+func RunScenarioFile(t *ldtest.T, f ScenarioFile) {
+	for _, s := range f.Scenarios {
+		t.Run(s.Name, s.runner(nil, nil))
+	}
+}
+
+// runEmbeddedScenarios runs the scenarios in a file that is built into the test harness.
+func runEmbeddedScenarios(t *ldtest.T, fileName string) {
+	f, err := LoadScenarioFile(embeddedScenarios, path.Join("scenarios", fileName))
+	require.NoError(t, err)
+	RunScenarioFile(t, f)
+}
+
+func (s Scenario) validate() error {
+	if s.Name == "" {
+		return errors.New("scenario has no name")
+	}
+	if (len(s.Steps) == 0) == (len(s.Scenarios) == 0) {
+		return fmt.Errorf("scenario %q must have either steps or scenarios, but not both", s.Name)
+	}
+	for _, size := range s.ChunkSizes {
+		if size < 0 {
+			return fmt.Errorf("scenario %q has a negative chunk size", s.Name)
+		}
+	}
+	for i, step := range s.Steps {
+		if err := step.validate(); err != nil {
+			return fmt.Errorf("scenario %q, step %d: %w", s.Name, i+1, err)
+		}
+	}
+	for _, child := range s.Scenarios {
+		if err := child.validate(); err != nil {
+			return fmt.Errorf("scenario %q: %w", s.Name, err)
+		}
+	}
+	return nil
+}
+
+func (step ScenarioStep) validate() error {
+	count := 0
+	for _, isSet := range []bool{
+		step.Send != nil,
+		step.DelayMS != 0,
+		step.Break,
+		step.AwaitReconnect,
+		step.ExpectEvent != nil,
+		step.ExpectComment != nil,
+		step.ExpectError,
+	} {
+		if isSet {
+			count++
+		}
+	}
+	if count != 1 {
+		return errors.New("step must have exactly one action")
+	}
+	if step.DelayMS < 0 {
+		return errors.New("delayMs cannot be negative")
+	}
+	return nil
+}
+
+func (s Scenario) runner(inheritedVars map[string]string, inheritedChunkSizes []int) func(t *ldtest.T) {
+	vars := make(map[string]string)
+	for k, v := range inheritedVars {
+		vars[k] = v
+	}
+	for k, v := range s.Vars {
+		vars[k] = v
+	}
+	chunkSizes := inheritedChunkSizes
+	if len(s.ChunkSizes) != 0 {
+		chunkSizes = s.ChunkSizes
+	}
+
+	return func(t *ldtest.T) {
+		for _, c := range s.Requires {
+			t.RequireCapability(c)
+		}
+		for _, child := range s.Scenarios {
+			t.Run(child.Name, child.runner(vars, chunkSizes))
+		}
+		if len(s.Steps) == 0 {
+			return
+		}
+		if len(chunkSizes) == 0 {
+			s.runSteps(t, vars, 0)
+			return
+		}
+		for _, size := range chunkSizes {
+			size := size
+			t.Run(chunkSizeDescription(size), func(t *ldtest.T) { s.runSteps(t, vars, size) })
+		}
+	}
+}
+
+func (s Scenario) runSteps(t *ldtest.T, vars map[string]string, chunkSize int) {
+	var replacements []string
+	for k, v := range vars {
+		replacements = append(replacements, "${"+k+"}", v)
+	}
+	replacer := strings.NewReplacer(replacements...)
+
+	server, stream, client := NewStreamAndSSEClient(t)
+	for _, step := range s.Steps {
+		if step.ExpectEvent != nil && step.ExpectEvent.Type != "" {
+			client.BePreparedToReceiveEventType(t, step.ExpectEvent.Type)
+		}
+	}
+
+	for _, step := range s.Steps {
+		switch {
+		case step.Send != nil:
+			data := replacer.Replace(*step.Send)
+			if chunkSize == 0 {
+				stream.Send(data)
+			} else {
+				stream.SendInChunks(data, chunkSize, scenarioChunkDelay)
+			}
+		case step.DelayMS != 0:
+			time.Sleep(time.Duration(step.DelayMS) * time.Millisecond)
+		case step.Break:
+			stream.BreakConnection()
+			client.IgnoreErrorHere()
+		case step.AwaitReconnect:
+			stream = server.AwaitConnection(t)
+		case step.ExpectEvent != nil:
+			client.RequireSpecificEvents(t, *step.ExpectEvent)
+		case step.ExpectComment != nil:
+			if t.Capabilities().Has("comments") {
+				assert.Equal(t, *step.ExpectComment, client.RequireComment(t))
+			}
+		case step.ExpectError:
+			client.RequireError(t)
+		}
+	}
+}
+
+func chunkSizeDescription(size int) string {
+	if size == 0 {
+		return "one chunk"
+	}
+	return strconv.Itoa(size) + "-character chunks"
+}
+
+// RunScenarioSuite runs the scenarios in the specified files, instead of the usual test suite.
+// Each file's scenarios are in a test group called "scenarios/<file name>".
+func RunScenarioSuite(
+	harness *harness.TestHarness,
+	files []ScenarioFile,
+	filter ldtest.Filter,
+	testLogger ldtest.TestLogger,
+) ldtest.Results {
+	config := ldtest.TestConfiguration{
+		Filter:       filter,
+		Capabilities: harness.TestServiceInfo().Capabilities,
+		TestLogger:   testLogger,
+		Context: SSETestContext{
+			harness: harness,
+		},
+	}
+
+	return ldtest.Run(config, func(t *ldtest.T) {
+		t.Run("scenarios", func(t *ldtest.T) { RunScenarioFiles(t, files) })
+	})
+}
@@ -0,0 +1,135 @@
+package ssetests
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+	"time"
+)
+
+// sentEventLog records each event that the stream server has finished sending, in order, across
+// all of its connections. The SSE client uses this to work out how long each event took to be
+// delivered: if the Nth event it receives has the same data as the Nth one that was sent, it is
+// most likely the same event.
+type sentEventLog struct {
+	events []sentEventInfo
+	lock   sync.Mutex
+}
+
+type sentEventInfo struct {
+	sentAt   time.Time
+	dataHash uint64
+}
+
+func (l *sentEventLog) add(sentAt time.Time, dataHashes []uint64) {
+	l.lock.Lock()
+	for _, h := range dataHashes {
+		l.events = append(l.events, sentEventInfo{sentAt: sentAt, dataHash: h})
+	}
+	l.lock.Unlock()
+}
+
+// get returns information about the event with the specified index, if it has been sent.
+func (l *sentEventLog) get(index int) (sentEventInfo, bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if index < 0 || index >= len(l.events) {
+		return sentEventInfo{}, false
+	}
+	return l.events[index], true
+}
+
+// hashEventData returns the hash that eventBoundaryScanner computes for an event with this data.
+func hashEventData(data string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(data))
+	return h.Sum64()
+}
+
+// eventBoundaryScanner finds the points in a stream where an SSE client should dispatch an event,
+// that is, blank lines that come after at least one "data" field, and works out what the data of
+// each event should be. It only understands as much of the SSE format as it needs to for that,
+// and assumes the stream is valid UTF-8.
+type eventBoundaryScanner struct {
+	bomBytes  int // how many bytes of a BOM we have skipped, or len(utf8BOM) if we are past it
+	lastWasCR bool
+	line      []byte
+	data      []byte
+	hasData   bool
+}
+
+// scan consumes the next chunk of the stream and returns the data hashes of the events that it
+// completes.
+func (s *eventBoundaryScanner) scan(chunk []byte) []uint64 {
+	var completed []uint64
+	for _, b := range chunk {
+		if s.bomBytes < len(utf8BOM) {
+			if b == utf8BOM[s.bomBytes] {
+				s.bomBytes++
+				continue
+			}
+			s.bomBytes = len(utf8BOM)
+		}
+		if s.lastWasCR {
+			s.lastWasCR = false
+			if b == '\n' {
+				continue
+			}
+		}
+		if b == '\r' || b == '\n' {
+			s.lastWasCR = b == '\r'
+			if s.endLine() {
+				completed = append(completed, hashEventData(string(s.data)))
+				s.data = s.data[:0]
+			}
+			continue
+		}
+		s.line = append(s.line, b)
+	}
+	return completed
+}
+
+// endLine processes the line that has just ended, and returns true if it dispatches an event.
+func (s *eventBoundaryScanner) endLine() bool {
+	line := s.line
+	s.line = s.line[:0]
+	if len(line) == 0 {
+		dispatch := s.hasData
+		s.hasData = false
+		return dispatch
+	}
+	name, value := line, []byte(nil)
+	for i, b := range line {
+		if b == ':' {
+			name, value = line[:i], line[i+1:]
+			if len(value) > 0 && value[0] == ' ' {
+				value = value[1:]
+			}
+			break
+		}
+	}
+	if string(name) == "data" {
+		if s.hasData {
+			s.data = append(s.data, '\n')
+		}
+		s.data = append(s.data, value...)
+		s.hasData = true
+	}
+	return false
+}
+
+// describeLatencies summarizes a latency distribution as percentiles.
+func describeLatencies(latencies []time.Duration) string {
+	if len(latencies) == 0 {
+		return "no events"
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return fmt.Sprintf("p50 %s, p90 %s, p99 %s, max %s",
+		percentile(50).Round(time.Microsecond), percentile(90).Round(time.Microsecond),
+		percentile(99).Round(time.Microsecond), sorted[len(sorted)-1].Round(time.Microsecond))
+}
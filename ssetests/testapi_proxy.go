@@ -0,0 +1,76 @@
+package ssetests
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/stretchr/testify/require"
+)
+
+const awaitProxyRequestTimeout = time.Second * 5
+
+// ProxyServer is a forward proxy that the SSE client can be told to use. Like StreamServer, it can
+// be passed to NewSSEClient as a configurer, which sets the proxy URL in the client parameters.
+type ProxyServer struct {
+	proxy   *harness.Proxy
+	options harness.ProxyOptions
+}
+
+// NewProxyServer starts a forward proxy that will be closed at the end of the test. If
+// options.Username is set, the proxy requires those credentials, and ApplyConfiguration includes
+// them in the proxy URL.
+func NewProxyServer(t *ldtest.T, options harness.ProxyOptions) *ProxyServer {
+	proxy, err := requireContext(t).harness.NewProxy(options, t.DebugLogger())
+	require.NoError(t, err)
+	t.Defer(proxy.Close)
+	return &ProxyServer{proxy: proxy, options: options}
+}
+
+func (p *ProxyServer) ApplyConfiguration(params *servicedef.CreateStreamParams) {
+	params.ProxyURL = p.URLWithCredentials(p.options.Username, p.options.Password)
+}
+
+// URLWithCredentials returns the proxy URL with the specified credentials in it, or without any
+// credentials if username is empty.
+func (p *ProxyServer) URLWithCredentials(username, password string) string {
+	if username == "" {
+		return p.proxy.URL()
+	}
+	u, _ := url.Parse(p.proxy.URL())
+	u.User = url.UserPassword(username, password)
+	return u.String()
+}
+
+// AwaitRequest waits for the next request to the proxy.
+func (p *ProxyServer) AwaitRequest(t *ldtest.T) harness.ProxiedRequest {
+	r, err := p.proxy.AwaitRequest(awaitProxyRequestTimeout)
+	require.NoError(t, err)
+	return r
+}
+
+// RequireRequestTo waits for the next request to the proxy that was allowed through, and verifies
+// that it was for the stream server. A client can use CONNECT even for a plain HTTP target, so the
+// target can either be the stream URL or just its host.
+func (p *ProxyServer) RequireRequestTo(t *ldtest.T, server *StreamServer) harness.ProxiedRequest {
+	for {
+		r := p.AwaitRequest(t)
+		if !r.Authorized {
+			// A client might send credentials only after the proxy has asked for them.
+			continue
+		}
+		streamURL := server.endpoint.BaseURL()
+		if r.Method == "CONNECT" {
+			u, _ := url.Parse(streamURL)
+			require.Equal(t, u.Host, r.Target, "proxy tunnel was not for the stream server")
+		} else if !strings.HasPrefix(r.Target, streamURL) {
+			require.Fail(t, "proxied request was not for the stream server",
+				"expected %s but got %s", streamURL, r.Target)
+		}
+		return r
+	}
+}
@@ -1,13 +1,21 @@
 package ssetests
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/launchdarkly/sse-contract-tests/framework"
 	"github.com/launchdarkly/sse-contract-tests/framework/harness"
@@ -18,14 +26,54 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-const awaitMessageTimeout = time.Second * 5
+const (
+	awaitMessageTimeout = time.Second * 5
+
+	// callbackGapTimeout is how long we will wait for a callback that is missing from the sequence,
+	// after a later one has arrived, before we decide that the test service skipped it.
+	callbackGapTimeout       = time.Second * 2
+	callbackGapCheckInterval = time.Millisecond * 100
+
+	pollInterval = time.Millisecond * 20
+
+	// maxPollFailures is how many polling requests in a row can fail before we give up on the test
+	// service. A single failure may be transient, so we just try again on the next tick.
+	maxPollFailures = 10
+)
+
+var errMessageTimeout = errors.New("timed out waiting for message from test service entity")
 
 type SSEClient struct {
 	service         *harness.TestServiceEntity
 	outputCh        chan messageOrError
 	callbackQueue   *harness.MessageSortingQueue
+	acceptBatches   bool
 	ignoreNextError bool
+	ignoreComments  bool
+	quiet           int32 // accessed atomically, since consumeCallbacks runs on another goroutine
 	logger          framework.Logger
+	closeOnce       sync.Once
+	closeErr        error
+
+	// lastStreamCounter is the number of messages received by handleCallbackStream. The test
+	// service should only open one callback stream, but if it opens another, it waits on
+	// callbackStreamLock until the first one is closed, so the messages stay in order.
+	lastStreamCounter  int
+	callbackStreamLock sync.Mutex
+
+	// server is the StreamServer that the client was created with, if any. We use its record of
+	// when each event was sent to work out how long the event took to be delivered, assuming that
+	// the Nth event we receive is the Nth one it sent as long as their data is the same.
+	server *StreamServer
+
+	// receivedAt holds the time when each callback arrived, by counter, until consumeCallbacks
+	// takes it. latencies holds the delivery time of every event that we could match up with the
+	// time it was sent. If an event did not match, unmatchedEvent is its 1-based position; we stop
+	// matching from then on, since every later event would probably be matched with the wrong one.
+	receivedAt     map[int]time.Time
+	latencies      []time.Duration
+	unmatchedEvent int
+	timingsLock    sync.Mutex
 }
 
 type SSEClientConfigurer interface {
@@ -34,7 +82,7 @@ type SSEClientConfigurer interface {
 
 // ReceivedMessage is a single message sent to us by the test service.
 type ReceivedMessage struct {
-	// Kind is "event", "comment", or "error".
+	// Kind is "event", "comment", "error", "opened", or "closed".
 	Kind string `json:"kind"`
 
 	// Event is non-nil if Kind is "event". It contains an SSE event that was received by the
@@ -48,6 +96,25 @@ type ReceivedMessage struct {
 	// Error contains an error message from the test service, if Kind is "error".
 	Error string `json:"error,omitempty"`
 
+	// ErrorCategory is one of the servicedef.ErrorCategory constants, if Kind is "error" and the
+	// test service has the "error-categories" capability.
+	ErrorCategory string `json:"errorCategory,omitempty"`
+
+	// HTTPStatus is the HTTP status that caused an error, if ErrorCategory is "http_status" or
+	// "redirect".
+	HTTPStatus int `json:"httpStatus,omitempty"`
+
+	// WillRetry says whether the client will reconnect after an error, if the test service knows.
+	WillRetry *bool `json:"willRetry,omitempty"`
+
+	// ReceivedAt is the time when the test harness received this message from the test service.
+	ReceivedAt time.Time `json:"-"`
+
+	// SentAt is the time when the stream server sent the end of this event, if Kind is "event", the
+	// client is connected to a StreamServer, and the event could be matched with one that the server
+	// sent. Otherwise it is zero.
+	SentAt time.Time `json:"-"`
+
 	raw string // The original JSON, for debug logging
 }
 
@@ -59,6 +126,11 @@ type EventMessage struct {
 	Type string `json:"type"`
 	Data string `json:"data"`
 	ID   string `json:"id"`
+
+	// DataBase64 is the same as Data, encoded in base64, if the test service has the "data-base64"
+	// capability. It lets us see exactly what bytes the client produced, even if the test service's
+	// JSON encoder did something unexpected with the data string.
+	DataBase64 string `json:"dataBase64,omitempty" yaml:"-"`
 }
 
 func (e EventMessage) String() string {
@@ -66,6 +138,11 @@ func (e EventMessage) String() string {
 	return string(data)
 }
 
+type polledMessage struct {
+	Counter int             `json:"counter"`
+	Message json.RawMessage `json:"message"`
+}
+
 type messageOrError struct {
 	message ReceivedMessage
 	err     error
@@ -77,33 +154,60 @@ func NewSSEClient(t *ldtest.T, configurers ...SSEClientConfigurer) *SSEClient {
 	testHarness := requireContext(t).harness
 
 	params := servicedef.CreateStreamParams{}
+	var server *StreamServer
 	for _, conf := range configurers {
 		conf.ApplyConfiguration(&params)
+		if s, ok := conf.(*StreamServer); ok {
+			server = s
+		}
 	}
 	if params.StreamURL == "" {
 		require.Fail(t, "StreamURL was not set in stream parameters; did you forget to reference the StreamServer?")
 	}
+	if server != nil && !strings.HasPrefix(params.StreamURL, server.endpoint.BaseURL()) {
+		server = nil // a later configurer pointed the client somewhere else
+	}
 	params.Tag = t.ID().String()
 	c := &SSEClient{
 		outputCh:      make(chan messageOrError, 100),
 		callbackQueue: harness.NewMessageSortingQueue(100),
+		acceptBatches: t.Capabilities().Has("callback-batch"),
 		logger:        t.DebugLogger(),
+		server:        server,
+		receivedAt:    make(map[int]time.Time),
 	}
 	t.Defer(c.callbackQueue.Close)
-
-	callbackEndpoint := testHarness.NewMockEndpoint(http.HandlerFunc(c.handleCallback), nil, t.DebugLogger())
-	t.Defer(callbackEndpoint.Close)
-
-	params.CallbackURL = callbackEndpoint.BaseURL()
+	t.Defer(func() { c.reportLatencies(t) }) // this runs last, after the client has stopped
+
+	var callbackEndpoint *harness.MockEndpoint
+	switch {
+	case t.Capabilities().Has("callback-stream"):
+		params.CallbackTransport = servicedef.CallbackTransportStream
+		callbackEndpoint = testHarness.NewStreamingMockEndpoint(
+			http.HandlerFunc(c.handleCallbackStream), nil, t.DebugLogger())
+	case t.Capabilities().Has("callback-poll"):
+		params.CallbackTransport = servicedef.CallbackTransportPoll
+	default:
+		callbackEndpoint = testHarness.NewMockEndpoint(http.HandlerFunc(c.handleCallback), nil, t.DebugLogger())
+	}
+	if callbackEndpoint != nil {
+		t.Defer(callbackEndpoint.Close)
+		params.CallbackURL = callbackEndpoint.BaseURL()
+	}
 
 	service, err := testHarness.NewTestServiceEntity(params, "SSE client", t.DebugLogger())
 	require.NoError(t, err)
+	c.service = service
 	t.Defer(func() {
-		_ = service.Close()
+		_ = c.closeService()
 	})
-	c.service = service
 
 	go c.consumeCallbacks()
+	if params.CallbackTransport == servicedef.CallbackTransportPoll {
+		stopCh := make(chan struct{})
+		t.Defer(func() { close(stopCh) }) // this runs before the entity is closed
+		go c.pollMessages(stopCh)
+	}
 
 	return c
 }
@@ -125,7 +229,33 @@ func (c *SSEClient) handleCallback(w http.ResponseWriter, req *http.Request) {
 	if req.URL.Path != "" || req.URL.Path == "/" {
 		counter, err := strconv.Atoi(req.URL.Path[1:])
 		if err == nil {
-			c.callbackQueue.Accept(counter, data)
+			if trimmed := bytes.TrimSpace(data); len(trimmed) != 0 && trimmed[0] == '[' {
+				// This is a batch of messages, the first of which has the counter in the URL.
+				if !c.acceptBatches {
+					c.outputError(errors.New(
+						`test service sent a batch of callbacks without declaring the "callback-batch" capability`))
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				var batch []json.RawMessage
+				if err := json.Unmarshal(trimmed, &batch); err != nil || len(batch) == 0 {
+					c.outputError(fmt.Errorf("malformed or empty JSON array from test service: %s", data))
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				messages := make([][]byte, 0, len(batch))
+				for _, m := range batch {
+					messages = append(messages, m)
+				}
+				err = c.acceptCallbacks(counter, messages...)
+			} else {
+				err = c.acceptCallbacks(counter, data)
+			}
+			if err != nil {
+				c.outputError(err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
 			w.WriteHeader(http.StatusAccepted)
 			return
 		}
@@ -134,16 +264,119 @@ func (c *SSEClient) handleCallback(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusBadRequest)
 }
 
+// handleCallbackStream receives all of the callback messages for this stream over a single
+// request, as newline-delimited JSON. Since they all arrive in order on the same connection, they
+// do not have counters in the URL; we number them ourselves as they arrive, so that the rest of
+// the callback logic is the same as for individual requests.
+func (c *SSEClient) handleCallbackStream(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" || req.URL.Path != servicedef.CallbackStreamPath {
+		c.outputError(fmt.Errorf("expected callback stream request to %s, got %s %s",
+			servicedef.CallbackStreamPath, req.Method, req.URL.Path))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	defer func() { _ = req.Body.Close() }()
+	c.callbackStreamLock.Lock()
+	defer c.callbackStreamLock.Unlock()
+	c.logger.Printf("Callback stream opened")
+	reader := bufio.NewReader(req.Body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) != 0 {
+			c.lastStreamCounter++
+			if err := c.acceptCallbacks(c.lastStreamCounter, line); err != nil {
+				c.outputError(err)
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				c.logger.Printf("Callback stream failed: %s", err)
+			}
+			break
+		}
+	}
+	c.logger.Printf("Callback stream closed")
+	w.WriteHeader(http.StatusOK)
+}
+
+// acceptCallbacks records the time when messages with consecutive counters arrived, and then adds
+// them to the callback queue.
+func (c *SSEClient) acceptCallbacks(firstCounter int, messages ...[]byte) error {
+	now := time.Now()
+	c.timingsLock.Lock()
+	for i := range messages {
+		// If a counter is already here, this is a duplicate, which the queue will reject.
+		if _, ok := c.receivedAt[firstCounter+i]; !ok {
+			c.receivedAt[firstCounter+i] = now
+		}
+	}
+	c.timingsLock.Unlock()
+	return c.callbackQueue.AcceptBatch(firstCounter, messages)
+}
+
+// pollMessages repeatedly asks the test service for messages, if it cannot send them to us. Each
+// response contains every message with a counter higher than the highest one we have seen.
+func (c *SSEClient) pollMessages(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	highestCounter, failures := 0, 0
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+		var items []polledMessage
+		path := fmt.Sprintf("%s?after=%d", servicedef.PolledMessagesPath, highestCounter)
+		if err := c.service.Get(path, c.logger, &items); err != nil {
+			select {
+			case <-stopCh: // the test has ended, so the entity may already be gone
+				return
+			default:
+			}
+			failures++
+			if failures < maxPollFailures {
+				c.logger.Printf("Polling for messages failed, will retry: %s", err)
+				continue
+			}
+			c.outputError(fmt.Errorf("polling for messages failed %d times in a row, giving up: %w", failures, err))
+			return
+		}
+		failures = 0
+		for _, item := range items {
+			if err := c.acceptCallbacks(item.Counter, item.Message); err != nil {
+				c.outputError(err)
+				continue
+			}
+			if item.Counter > highestCounter {
+				highestCounter = item.Counter
+			}
+		}
+	}
+}
+
 // AwaitMessage waits until the test service sends a message.
+//
+// It returns an error if the test service sent an invalid callback request, or if it has left a
+// gap in the callback counters for longer than callbackGapTimeout, since in that case no more
+// messages will be delivered.
 func (c *SSEClient) AwaitMessage(timeout time.Duration) (ReceivedMessage, error) {
 	deadline := time.NewTimer(timeout)
 	defer deadline.Stop()
+	gapCheck := time.NewTicker(callbackGapCheckInterval)
+	defer gapCheck.Stop()
 	for {
 		select {
 		case item, ok := <-c.outputCh:
 			if !ok {
 				return ReceivedMessage{}, errors.New("callback endpoint was already closed")
 			}
+			if item.err != nil {
+				return ReceivedMessage{}, fmt.Errorf("invalid callback from test service: %w", item.err)
+			}
+			if c.ignoreComments && item.message.Kind == servicedef.CallbackKindComment {
+				continue
+			}
 			if c.ignoreNextError {
 				c.ignoreNextError = false
 				if item.message.Kind == "error" {
@@ -151,8 +384,15 @@ func (c *SSEClient) AwaitMessage(timeout time.Duration) (ReceivedMessage, error)
 				}
 			}
 			return item.message, nil
+		case <-gapCheck.C:
+			if gap, hasGap := c.callbackQueue.Gap(); hasGap && time.Since(gap.Since) >= callbackGapTimeout {
+				return ReceivedMessage{}, fmt.Errorf("test service skipped a callback: %s", gap)
+			}
 		case <-deadline.C:
-			return ReceivedMessage{}, errors.New("timed out waiting for message from test service entity")
+			if gap, hasGap := c.callbackQueue.Gap(); hasGap {
+				return ReceivedMessage{}, fmt.Errorf("%w; %s", errMessageTimeout, gap)
+			}
+			return ReceivedMessage{}, errMessageTimeout
 		}
 	}
 }
@@ -166,6 +406,44 @@ func (c *SSEClient) RequireMessage(t *ldtest.T) ReceivedMessage {
 	return m
 }
 
+// RequireNoMessage verifies that the SSE client in the test service does not send us anything
+// within the specified time.
+//
+// The test fails and immediately exits if any message is received, or if the test service sent an
+// invalid callback.
+func (c *SSEClient) RequireNoMessage(t *ldtest.T, timeout time.Duration) {
+	m, err := c.AwaitMessage(timeout)
+	if err == nil {
+		require.Fail(t, "received an unexpected message", "expected no message but got: %s", m)
+	}
+	if err != errMessageTimeout { // if the error wraps errMessageTimeout, a callback was skipped
+		require.NoError(t, err)
+	}
+}
+
+// RequireNoEvent verifies that the SSE client in the test service does not tell us that it
+// received an event within the specified time. Any other kinds of messages are ignored.
+//
+// The test fails and immediately exits if an event is received, or if the test service sent an
+// invalid callback.
+func (c *SSEClient) RequireNoEvent(t *ldtest.T, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return
+		}
+		m, err := c.AwaitMessage(remaining)
+		if err == errMessageTimeout {
+			return
+		}
+		require.NoError(t, err)
+		if m.Kind == servicedef.CallbackKindEvent {
+			require.Fail(t, "received an unexpected event", "expected no event but got: %s", m)
+		}
+	}
+}
+
 func (c *SSEClient) requireMessageOfKind(t *ldtest.T, kind string) ReceivedMessage {
 	m := c.RequireMessage(t)
 	if m.Kind != kind {
@@ -184,6 +462,27 @@ func (c *SSEClient) RequireEvent(t *ldtest.T) EventMessage {
 	return *e
 }
 
+// RequireEventWithin waits for the SSE client in the test service to tell us that it received an
+// event, and verifies that the event was delivered within the specified time after sentAt, which is
+// normally the time returned by StreamConnection.SendAndWait for the data that completed the event.
+//
+// This catches clients that do not dispatch an event as soon as it is complete, but wait for more
+// data to arrive first. The test fails and immediately exits if the event was not delivered in
+// time, or if what we receive from the test service is not an event.
+func (c *SSEClient) RequireEventWithin(t *ldtest.T, sentAt time.Time, maxLatency time.Duration) EventMessage {
+	m, err := c.AwaitMessage(time.Until(sentAt.Add(maxLatency)) + awaitMessageTimeout)
+	require.NoError(t, err)
+	if m.Kind != servicedef.CallbackKindEvent || m.Event == nil {
+		require.Fail(t, "received an unexpected message", "expected an event but got: %s", m)
+	}
+	if latency := m.ReceivedAt.Sub(sentAt); latency > maxLatency {
+		require.Fail(t, "event was not delivered in time",
+			"expected the event to be delivered within %s after it was sent, but it took %s: %s",
+			maxLatency, latency.Round(time.Millisecond), m)
+	}
+	return *m.Event
+}
+
 // RequireError waits for the SSE client in the test service to tell us that it received an error.
 //
 // The test fails and immediately exits if it times out without receiving anything, or if what we
@@ -192,6 +491,52 @@ func (c *SSEClient) RequireError(t *ldtest.T) string {
 	return c.requireMessageOfKind(t, "error").Error
 }
 
+// RequireOpened waits for the SSE client in the test service to tell us that it has successfully
+// connected to the stream. This requires the "connection-state" capability, and the stream must
+// have been created with ReportConnectionState.
+//
+// The test fails and immediately exits if it times out without receiving anything, or if what we
+// receive from the test service is anything other than an "opened" message.
+func (c *SSEClient) RequireOpened(t *ldtest.T) {
+	c.requireMessageOfKind(t, servicedef.CallbackKindOpened)
+}
+
+// RequireClosed waits for the SSE client in the test service to tell us that its connection to
+// the stream was closed. This requires the "connection-state" capability, and the stream must
+// have been created with ReportConnectionState.
+//
+// Since SSE clients differ as to whether they report a dropped connection as an error, and as to
+// whether they do so before or after the state change, any error messages received before the
+// "closed" message are ignored. An error that comes after it can be skipped with IgnoreErrorHere.
+func (c *SSEClient) RequireClosed(t *ldtest.T) {
+	for {
+		m := c.RequireMessage(t)
+		switch m.Kind {
+		case servicedef.CallbackKindClosed:
+			return
+		case servicedef.CallbackKindError:
+			continue
+		default:
+			require.Fail(t, "received an unexpected message", "expected %q but got: %s",
+				servicedef.CallbackKindClosed, m)
+		}
+	}
+}
+
+// RequireErrorOfCategory waits for the SSE client in the test service to tell us that it received
+// an error, which must be of the specified category (one of the servicedef.ErrorCategory
+// constants). It returns the whole message, so that the test can check other details.
+//
+// If the test service does not have the "error-categories" capability, any error is accepted.
+func (c *SSEClient) RequireErrorOfCategory(t *ldtest.T, category string) ReceivedMessage {
+	m := c.requireMessageOfKind(t, "error")
+	if t.Capabilities().Has("error-categories") && m.ErrorCategory != category {
+		require.Fail(t, "received an error of the wrong category",
+			"expected %q but got: %s", category, m)
+	}
+	return m
+}
+
 // IgnoreErrorHere specifies that the next message from the client should be ignored if and
 // only if it is an error.
 //
@@ -204,6 +549,21 @@ func (c *SSEClient) IgnoreErrorHere() {
 	c.ignoreNextError = true
 }
 
+// DisableMessageLogging stops the client from writing every message that it receives to the debug
+// output. This is for tests that receive so many messages that the output would be unmanageable.
+func (c *SSEClient) DisableMessageLogging() {
+	atomic.StoreInt32(&c.quiet, 1)
+}
+
+// IgnoreComments specifies that all comment messages from the client should be ignored from now on.
+//
+// This is for tests where the stream contains comments that are only there for their effect on the
+// connection, such as heartbeats, so that the test behaves the same way whether or not the client
+// has the "comments" capability.
+func (c *SSEClient) IgnoreComments() {
+	c.ignoreComments = true
+}
+
 // RequireSpecificEvents waits for the SSE client in the test service to tell us that it received
 // a series of events, which must match the specified events.
 //
@@ -216,12 +576,36 @@ func (c *SSEClient) RequireSpecificEvents(t *ldtest.T, events ...EventMessage) {
 		if expected.Type == "" {
 			expected.Type = "message"
 		}
-		actual := c.RequireEvent(t)
-		if actual.Type == "" {
-			actual.Type = "message"
+		assertEventMatches(t, expected, c.RequireEvent(t))
+	}
+}
+
+// assertEventMatches compares an event that the client received with the one that we expected,
+// treating event types of "message" and "" as equal. If the test service provided DataBase64, the
+// data is compared as bytes, and any difference between it and Data is reported separately, since
+// that means the data was changed in the test service's JSON encoding rather than by the client.
+func assertEventMatches(t *ldtest.T, expected, actual EventMessage, msgAndArgs ...interface{}) {
+	if expected.Type == "" {
+		expected.Type = "message"
+	}
+	if actual.Type == "" {
+		actual.Type = "message"
+	}
+	expected.DataBase64 = ""
+	if actual.DataBase64 != "" {
+		raw, err := base64.StdEncoding.DecodeString(actual.DataBase64)
+		if err != nil {
+			assert.Fail(t, "test service sent invalid base64 data", "%q: %s", actual.DataBase64, err)
+		} else {
+			if actual.Data != string(raw) {
+				assert.Fail(t, "event data and dataBase64 do not match; the test service's JSON encoding changed the data",
+					"data was %q, but dataBase64 was %q", actual.Data, raw)
+			}
+			actual.Data = string(raw)
 		}
-		assert.Equal(t, expected, actual)
+		actual.DataBase64 = ""
 	}
+	assert.Equal(t, expected, actual, msgAndArgs...)
 }
 
 // RequireComment waits for the SSE client in the test service to tell us that it received a comment.
@@ -232,10 +616,47 @@ func (c *SSEClient) RequireComment(t *ldtest.T) string {
 	return c.requireMessageOfKind(t, "comment").Comment
 }
 
+// Close tells the test service to stop the SSE client right away, rather than at the end of the
+// test. It is safe to call more than once.
+func (c *SSEClient) Close(t *ldtest.T) {
+	require.NoError(t, c.closeService())
+}
+
+func (c *SSEClient) closeService() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.service.Close()
+	})
+	return c.closeErr
+}
+
 // Restart tells the SSE client in the test service to immediately disconnect and retry.
 // Not all SSE implementations support this.
 func (c *SSEClient) Restart(t *ldtest.T) {
-	require.NoError(t, c.service.SendCommand("restart", c.logger, nil))
+	require.NoError(t, c.service.SendCommand(servicedef.CommandRestart, c.logger, nil))
+}
+
+// SetHeaders tells the SSE client in the test service to use a new set of custom headers, instead
+// of the ones it was created with, the next time it connects. It does not make it reconnect.
+func (c *SSEClient) SetHeaders(t *ldtest.T, headers map[string]string) {
+	require.NoError(t, c.service.SendCommandWithParams(
+		servicedef.CommandParams{
+			Command:    servicedef.CommandSetHeaders,
+			SetHeaders: &servicedef.SetHeadersParams{Headers: headers},
+		},
+		c.logger,
+		nil))
+}
+
+// SetURL tells the SSE client in the test service to use a new stream URL the next time it
+// connects. It does not make it reconnect.
+func (c *SSEClient) SetURL(t *ldtest.T, streamURL string) {
+	require.NoError(t, c.service.SendCommandWithParams(
+		servicedef.CommandParams{
+			Command: servicedef.CommandSetURL,
+			SetURL:  &servicedef.SetURLParams{StreamURL: streamURL},
+		},
+		c.logger,
+		nil))
 }
 
 // BePreparedToReceiveEventType tells the SSE client in the test service that it should be ready to
@@ -249,7 +670,7 @@ func (c *SSEClient) BePreparedToReceiveEventType(t *ldtest.T, eventType string)
 	}
 	require.NoError(t, c.service.SendCommandWithParams(
 		servicedef.CommandParams{
-			Command: "listen",
+			Command: servicedef.CommandListen,
 			Listen:  &servicedef.ListenParams{Type: eventType},
 		},
 		c.logger,
@@ -257,17 +678,74 @@ func (c *SSEClient) BePreparedToReceiveEventType(t *ldtest.T, eventType string)
 }
 
 func (c *SSEClient) consumeCallbacks() {
+	counter, eventCount := 0, 0
 	for data := range c.callbackQueue.C {
+		counter++ // the queue delivers the messages in counter order, starting at 1
+		c.timingsLock.Lock()
+		receivedAt := c.receivedAt[counter]
+		delete(c.receivedAt, counter)
+		c.timingsLock.Unlock()
+
 		message := ReceivedMessage{raw: string(data)}
+		if !utf8.Valid(data) {
+			// The JSON decoder would silently replace the invalid bytes with U+FFFD, which would make
+			// a client that does not decode the stream correctly look as if it did.
+			c.outputError(fmt.Errorf("test service sent invalid UTF-8 in JSON data: %q", message.raw))
+			continue
+		}
 		if err := json.Unmarshal(data, &message); err != nil {
 			c.outputError(fmt.Errorf("malformed JSON data from test service: %s", message.raw))
 			continue
 		}
-		c.logger.Printf("Received: %s", string(data))
+		message.ReceivedAt = receivedAt
+		if message.Kind == servicedef.CallbackKindEvent && message.Event != nil && c.server != nil {
+			c.matchSentEvent(&message, eventCount)
+			eventCount++
+		}
+		if atomic.LoadInt32(&c.quiet) == 0 {
+			c.logger.Printf("Received: %s", string(data))
+		}
 		c.outputCh <- messageOrError{message: message}
 	}
 }
 
+// matchSentEvent sets the SentAt time of the event with the specified 0-based position, if it has
+// the same data as the event that the stream server sent in that position.
+func (c *SSEClient) matchSentEvent(message *ReceivedMessage, index int) {
+	c.timingsLock.Lock()
+	defer c.timingsLock.Unlock()
+	if c.unmatchedEvent != 0 {
+		return
+	}
+	sent, ok := c.server.events.get(index)
+	if !ok || sent.dataHash != hashEventData(message.Event.Data) || message.ReceivedAt.Before(sent.sentAt) {
+		// The client may have skipped an event, for instance because of an event type listener, or
+		// produced one that the stream did not really contain.
+		c.unmatchedEvent = index + 1
+		c.logger.Printf("Event %d did not match the events that were sent; no longer measuring latency", index+1)
+		return
+	}
+	message.SentAt = sent.sentAt
+	c.latencies = append(c.latencies, message.ReceivedAt.Sub(sent.sentAt))
+}
+
+// reportLatencies adds a report to the test of how long events took to be delivered, if we were
+// able to measure that for any events.
+func (c *SSEClient) reportLatencies(t *ldtest.T) {
+	c.timingsLock.Lock()
+	latencies, unmatchedEvent := c.latencies, c.unmatchedEvent
+	c.timingsLock.Unlock()
+	switch {
+	case unmatchedEvent != 0 && len(latencies) == 0:
+		t.Report("event latency: not measured, because event %d did not match the events sent", unmatchedEvent)
+	case unmatchedEvent != 0:
+		t.Report("event latency (%d events, then stopped because event %d did not match the events sent): %s",
+			len(latencies), unmatchedEvent, describeLatencies(latencies))
+	case len(latencies) != 0:
+		t.Report("event latency (%d events): %s", len(latencies), describeLatencies(latencies))
+	}
+}
+
 func (c *SSEClient) outputError(err error) {
 	c.logger.Printf("Error: %s", err)
 	c.outputCh <- messageOrError{err: err}
@@ -1,8 +1,11 @@
 package ssetests
 
 import (
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"time"
 
@@ -14,15 +17,39 @@ import (
 
 const awaitConnectionTimeout = time.Second * 5
 
+// clientDisconnectTimeout is how long the SSE client has to close its connection after being told
+// to shut down.
+const clientDisconnectTimeout = time.Second * 2
+
+const (
+	// EncodingGzip is a value for WithCompression that makes the stream use gzip compression.
+	EncodingGzip = "gzip"
+
+	// EncodingDeflate is a value for WithCompression that makes the stream use deflate compression,
+	// which in HTTP means the zlib format.
+	EncodingDeflate = "deflate"
+)
+
 type StreamServer struct {
 	endpoint *harness.MockEndpoint
+	events   *sentEventLog
 	logger   framework.Logger
 }
 
+// StreamServerOption is an optional parameter for NewStreamServer.
+type StreamServerOption func(*streamServerConfig)
+
+type streamServerConfig struct {
+	encoding string
+	headers  http.Header
+	quiet    bool
+}
+
 type StreamConnection struct {
-	RequestInfo harness.IncomingRequestInfo
-	sendCh      chan<- streamChunk
-	logger      framework.Logger
+	RequestInfo    harness.IncomingRequestInfo
+	sendCh         chan<- streamChunk
+	disconnectedCh <-chan struct{}
+	logger         framework.Logger
 }
 
 type streamContextKeyType string
@@ -30,30 +57,72 @@ type streamContextKeyType string
 const streamContextKey streamContextKeyType = "ssetests.streamContext"
 
 type streamContext struct {
-	dataCh chan streamChunk
+	dataCh         chan streamChunk
+	disconnectedCh chan struct{}
 }
 
 type streamChunk struct {
 	data       []byte
 	delayAfter time.Duration
+	writtenCh  chan<- time.Time // if not nil, receives the time when the chunk was written
+}
+
+// WithCompression makes the stream server compress every response with the specified content
+// encoding, EncodingGzip or EncodingDeflate, regardless of the request's Accept-Encoding header.
+// Each chunk that is sent on the stream is flushed as a separate compressed block, so that the
+// client can decompress it without waiting for more data.
+func WithCompression(encoding string) StreamServerOption {
+	return func(c *streamServerConfig) { c.encoding = encoding }
+}
+
+// WithResponseHeaders changes the headers of the stream response. Each header replaces the default
+// header of the same name, if any. A header with no values is removed: for instance, setting
+// "Content-Type" to nil makes the response have no Content-Type at all.
+func WithResponseHeaders(headers http.Header) StreamServerOption {
+	return func(c *streamServerConfig) {
+		if c.headers == nil {
+			c.headers = make(http.Header)
+		}
+		for name, values := range headers {
+			c.headers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
 }
 
-func NewStreamServer(t *ldtest.T) *StreamServer {
+// WithoutDataLogging stops the stream server from writing everything that it sends to the debug
+// output. This is for tests that send so much data that the output would be unmanageable.
+func WithoutDataLogging() StreamServerOption {
+	return func(c *streamServerConfig) { c.quiet = true }
+}
+
+func NewStreamServer(t *ldtest.T, options ...StreamServerOption) *StreamServer {
+	var config streamServerConfig
+	for _, o := range options {
+		o(&config)
+	}
+	events := &sentEventLog{}
 	endpoint := requireContext(t).harness.NewMockEndpoint(
-		streamHandler(t.DebugLogger()),
+		streamHandler(config, events, t.DebugLogger()),
 		addStreamContext,
 		t.DebugLogger(),
 	)
 	t.Defer(func() {
 		endpoint.Close()
 	})
-	return &StreamServer{endpoint: endpoint, logger: t.DebugLogger()}
+	return &StreamServer{endpoint: endpoint, events: events, logger: t.DebugLogger()}
 }
 
 func (s *StreamServer) ApplyConfiguration(params *servicedef.CreateStreamParams) {
 	params.StreamURL = s.endpoint.BaseURL()
 }
 
+// URLWithPath returns a stream URL that consists of the server's base URL followed by relativeURI,
+// which can include a subpath and/or a query string. The stream server accepts requests for any
+// such URL, and the relative part can be checked in StreamConnection.RequestInfo.RequestURI.
+func (s *StreamServer) URLWithPath(relativeURI string) string {
+	return s.endpoint.BaseURL() + relativeURI
+}
+
 func (s *StreamServer) AwaitConnection(t *ldtest.T) *StreamConnection {
 	sc, err := s.AwaitConnectionWithTimeout(t, awaitConnectionTimeout)
 	if err != nil {
@@ -69,18 +138,47 @@ func (s *StreamServer) AwaitConnectionWithTimeout(t *ldtest.T, timeout time.Dura
 		t.Errorf("error: %s", err.Error())
 		t.FailNow()
 	}
-	dataCh := streamContextFromContext(requestInfo.Context).dataCh
+	sc := streamContextFromContext(requestInfo.Context)
 	return &StreamConnection{
-		RequestInfo: requestInfo,
-		sendCh:      dataCh,
-		logger:      s.logger,
+		RequestInfo:    requestInfo,
+		sendCh:         sc.dataCh,
+		disconnectedCh: sc.disconnectedCh,
+		logger:         s.logger,
 	}, nil
 }
 
+// RequireNoConnection verifies that the SSE client does not make a new request to the stream
+// within the specified time. A request that was made earlier but has not yet been returned by
+// AwaitConnection counts as a new one.
+func (s *StreamServer) RequireNoConnection(t *ldtest.T, timeout time.Duration) {
+	if requestInfo, err := s.endpoint.AwaitConnection(timeout); err == nil {
+		t.Errorf("error: SSE client made an unexpected %s request to the stream", requestInfo.Method)
+		t.FailNow()
+	}
+}
+
 func (sc *StreamConnection) Send(data string) {
 	sc.sendCh <- streamChunk{data: []byte(data)}
 }
 
+// SendAndWait is the same as Send, but waits until the stream server writes the data, and returns
+// the time just before it did so. This is for measuring how long the client takes to process it.
+//
+// The test fails and immediately exits if the data is not written within a few seconds, for
+// instance because the connection has been closed.
+func (sc *StreamConnection) SendAndWait(t *ldtest.T, data string) time.Time {
+	writtenCh := make(chan time.Time, 1)
+	sc.sendCh <- streamChunk{data: []byte(data), writtenCh: writtenCh}
+	select {
+	case writtenAt := <-writtenCh:
+		return writtenAt
+	case <-time.After(awaitConnectionTimeout):
+		t.Errorf("error: stream server did not send the data within %s", awaitConnectionTimeout)
+		t.FailNow()
+		return time.Time{}
+	}
+}
+
 func (sc *StreamConnection) SendInChunks(data string, chunkSize int, delayBetween time.Duration) {
 	bytes := []byte(data)
 	for pos := 0; pos < len(bytes); pos += chunkSize {
@@ -96,6 +194,23 @@ func (sc *StreamConnection) SendInChunks(data string, chunkSize int, delayBetwee
 	}
 }
 
+// ClientDisconnected returns a channel that is closed when the SSE client closes this connection.
+// It is not closed if the connection ended because of BreakConnection.
+func (sc *StreamConnection) ClientDisconnected() <-chan struct{} {
+	return sc.disconnectedCh
+}
+
+// RequireClientDisconnected verifies that the SSE client closes this connection within the
+// specified time, or has already closed it.
+func (sc *StreamConnection) RequireClientDisconnected(t *ldtest.T, timeout time.Duration) {
+	select {
+	case <-sc.disconnectedCh:
+	case <-time.After(timeout):
+		t.Errorf("error: SSE client did not close the stream connection within %s", timeout)
+		t.FailNow()
+	}
+}
+
 // BreakConnection closes the current connection.
 func (sc *StreamConnection) BreakConnection() {
 	sc.logger.Printf("Deliberately breaking stream connection")
@@ -103,8 +218,10 @@ func (sc *StreamConnection) BreakConnection() {
 }
 
 func addStreamContext(c context.Context) context.Context {
-	dataCh := make(chan streamChunk, 1000)
-	sc := streamContext{dataCh: dataCh}
+	sc := streamContext{
+		dataCh:         make(chan streamChunk, 1000),
+		disconnectedCh: make(chan struct{}),
+	}
 	return context.WithValue(c, streamContextKey, sc)
 }
 
@@ -115,7 +232,13 @@ func streamContextFromContext(c context.Context) streamContext {
 	panic("streamContext was not added to request context; this is a mistake in the program logic")
 }
 
-func streamHandler(logger framework.Logger) http.Handler {
+// compressingWriter is the subset of the gzip and zlib writers' methods that streamHandler uses.
+type compressingWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+func streamHandler(config streamServerConfig, events *sentEventLog, logger framework.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		closeNotifyCh := r.Context().Done()
 
@@ -127,8 +250,32 @@ func streamHandler(logger framework.Logger) http.Handler {
 		flusher := w.(http.Flusher)
 		w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+		for name, values := range config.headers {
+			if len(values) == 0 {
+				// Setting the header to nil, rather than deleting it, stops net/http from adding a
+				// Content-Type of its own based on the data.
+				w.Header()[name] = nil
+			} else {
+				w.Header()[name] = values
+			}
+		}
+		var out io.Writer = w
+		var compressor compressingWriter
+		switch config.encoding {
+		case EncodingGzip:
+			compressor = gzip.NewWriter(w)
+		case EncodingDeflate:
+			compressor = zlib.NewWriter(w)
+		}
+		if compressor != nil {
+			w.Header().Set("Content-Encoding", config.encoding)
+			out = compressor
+			// Flushing now sends the compression header, so the client can start reading right away.
+			_ = compressor.Flush()
+		}
 		flusher.Flush()
 
+		var scanner eventBoundaryScanner
 	Loop:
 		for {
 			select {
@@ -137,17 +284,35 @@ func streamHandler(logger framework.Logger) http.Handler {
 					break Loop
 				}
 				if chunk.data == nil { // indicates we want to break the connection
+					if compressor != nil {
+						_ = compressor.Close()
+					}
 					break Loop
 				}
-				chunkStr := string(chunk.data)
-				jsonStr, _ := json.Marshal(chunkStr)
-				logger.Printf("<< sending: %s", jsonStr)
-				_, _ = w.Write(chunk.data)
+				if !config.quiet {
+					jsonStr, _ := json.Marshal(string(chunk.data))
+					logger.Printf("<< sending: %s", jsonStr)
+				}
+				// The time is recorded before writing, since the client could receive the event before
+				// Write returns.
+				now := time.Now()
+				if hashes := scanner.scan(chunk.data); len(hashes) > 0 {
+					events.add(now, hashes)
+				}
+				if chunk.writtenCh != nil {
+					chunk.writtenCh <- now
+				}
+				_, _ = out.Write(chunk.data)
+				if compressor != nil {
+					_ = compressor.Flush()
+				}
 				flusher.Flush()
 				if chunk.delayAfter > 0 {
 					time.Sleep(chunk.delayAfter)
 				}
 			case <-closeNotifyCh:
+				logger.Printf("SSE client closed the stream connection")
+				close(sc.disconnectedCh)
 				break Loop
 			}
 		}
@@ -191,6 +191,99 @@ func DoBasicParsingTests(t *ldtest.T) {
 		)
 	})
 
+	// The following tests refer to the steps in "Interpreting an event stream" and "Dispatch the
+	// event" in the SSE specification: https://html.spec.whatwg.org/multipage/server-sent-events.html
+
+	t.Run("data value that is only a colon", func(t *ldtest.T) {
+		// "If the line contains a U+003A COLON character (:): collect the characters on the line
+		// after the first U+003A COLON character (:), and let value be that string." Only the first
+		// colon is a separator, so a second one is part of the value.
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send("data::\n\ndata: :\n\n")
+		client.RequireSpecificEvents(t,
+			EventMessage{Data: ":"},
+			EventMessage{Data: ":"},
+		)
+	})
+
+	t.Run("data value that is only two spaces", func(t *ldtest.T) {
+		// "If value starts with a U+0020 SPACE character, remove it from value." Only one space is
+		// removed, so the value is a single space.
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send("data:  \n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: " "})
+	})
+
+	t.Run("blank line with no preceding data is not dispatched", func(t *ldtest.T) {
+		// Dispatch the event, step 2: "If the data buffer is an empty string, set the data buffer
+		// and the event type buffer to the empty string and return." If these blank lines caused
+		// empty events, they would be received before the one we are expecting.
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send("\n\n\ndata: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+
+	t.Run("event with type but no data is not dispatched", func(t *ldtest.T) {
+		// Dispatch the event, step 2, as above; the event type buffer is also cleared, so the type
+		// does not carry over to the next event.
+		_, stream, client := NewStreamAndSSEClient(t)
+		client.BePreparedToReceiveEventType(t, "greeting")
+		stream.Send("event: greeting\n\ndata: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Type: "message", Data: "Hello"})
+	})
+
+	t.Run("event type is reset between events", func(t *ldtest.T) {
+		// Dispatch the event, step 7: "Set the data buffer and the event type buffer to the empty
+		// string." Unlike the last event ID, the type does not persist into the next event.
+		_, stream, client := NewStreamAndSSEClient(t)
+		client.BePreparedToReceiveEventType(t, "greeting")
+		stream.Send("event: greeting\ndata: Hello\n\ndata: World\n\n")
+		client.RequireSpecificEvents(t,
+			EventMessage{Type: "greeting", Data: "Hello"},
+			EventMessage{Type: "message", Data: "World"},
+		)
+	})
+
+	t.Run("very long line in an ignored field", func(t *ldtest.T) {
+		// "Otherwise: The field is ignored." The specification sets no limit on line length, so a
+		// field that is ignored must still be read in full to find where the next line starts.
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send("color: " + generateRandomString(1024*1024) + "\ndata: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+
+	t.Run("very long comment line", func(t *ldtest.T) {
+		// "If the line starts with a U+003A COLON character (:): Ignore the line." As above, it
+		// must still be read in full.
+		_, stream, client := NewStreamAndSSEClient(t)
+		comment := generateRandomString(1024 * 1024)
+		stream.Send(":" + comment + "\ndata: Hello\n\n")
+		if t.Capabilities().Has("comments") {
+			// Does not use assert.Equal, because then it would print megabytes of text.
+			if client.RequireComment(t) != comment {
+				assert.Fail(t, "Comment text did not match.")
+			}
+		}
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+
+	t.Run("event without trailing blank line is not dispatched", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send("data: Hello\n")
+		client.RequireNoEvent(t, time.Millisecond*500)
+
+		stream.BreakConnection()
+		client.RequireNoEvent(t, time.Millisecond*500)
+	})
+
+	t.Run("comments are not dispatched as events", func(t *ldtest.T) {
+		// This does not require the "comments" capability, since RequireNoEvent ignores comment
+		// messages if the test service does report them.
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.Send(":Hello\n\n:Goodbye\n\n")
+		client.RequireNoEvent(t, time.Millisecond*500)
+	})
+
 	t.Run("multi-byte characters", func(t *ldtest.T) {
 		_, stream, client := NewStreamAndSSEClient(t)
 		stream.Send("data: €豆腐\n\n")
@@ -0,0 +1,77 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// DoClientShutdownTests verifies that when the test harness closes the SSE client, the client
+// really does drop its HTTP connection, rather than just ceasing to report events, and that it does
+// not connect again afterward.
+func DoClientShutdownTests(t *ldtest.T) {
+	params := servicedef.CreateStreamParams{
+		InitialDelayMS: ldvalue.NewOptionalInt(0),
+	}
+
+	t.Run("closing client drops connection", func(t *ldtest.T) {
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+		client.Close(t)
+		stream.RequireClientDisconnected(t, clientDisconnectTimeout)
+		server.RequireNoConnection(t, time.Second)
+	})
+
+	t.Run("closing client before any events drops connection", func(t *ldtest.T) {
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		client.Close(t)
+		stream.RequireClientDisconnected(t, clientDisconnectTimeout)
+		server.RequireNoConnection(t, time.Second)
+	})
+
+	t.Run("closing client in the middle of an event drops connection", func(t *ldtest.T) {
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		stream.Send("data: Hello\n")
+		client.RequireNoEvent(t, time.Millisecond*100)
+
+		client.Close(t)
+		stream.RequireClientDisconnected(t, clientDisconnectTimeout)
+		server.RequireNoConnection(t, time.Second)
+	})
+
+	t.Run("closing client after reconnect drops new connection", func(t *ldtest.T) {
+		server, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		stream1.BreakConnection()
+		client.IgnoreErrorHere()
+		stream2 := server.AwaitConnection(t)
+
+		stream2.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+		client.Close(t)
+		stream2.RequireClientDisconnected(t, clientDisconnectTimeout)
+		server.RequireNoConnection(t, time.Second)
+	})
+
+	t.Run("closing client while waiting to reconnect prevents reconnect", func(t *ldtest.T) {
+		const reconnectDelay = time.Millisecond * 500
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+			InitialDelayMS: ldvalue.NewOptionalInt(int(reconnectDelay / time.Millisecond)),
+		}))
+
+		stream.BreakConnection()
+		client.IgnoreErrorHere()
+
+		client.Close(t)
+		server.RequireNoConnection(t, reconnectDelay*4)
+	})
+}
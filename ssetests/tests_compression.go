@@ -0,0 +1,100 @@
+package ssetests
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressedEventTimeout is how long we will wait for each event on a compressed stream. It is
+// shorter than usual, since an event that has been flushed should not be held up by decompression;
+// a client that waited for more data before decompressing would be much slower than this.
+const compressedEventTimeout = time.Second
+
+// DoCompressionTests verifies that the client can read compressed streams. Each encoding has its own
+// capability, "compression-gzip" or "compression-deflate", since many clients only support gzip.
+func DoCompressionTests(t *ldtest.T) {
+	for _, encoding := range []string{EncodingGzip, EncodingDeflate} {
+		encoding := encoding
+		t.Run(encoding, func(t *ldtest.T) {
+			t.RequireCapability("compression-" + encoding)
+
+			newCompressedStream := func(t *ldtest.T) (*StreamConnection, *SSEClient) {
+				server := NewStreamServer(t, WithCompression(encoding))
+				client := NewSSEClient(t, server)
+				return server.AwaitConnection(t), client
+			}
+
+			t.Run("client accepts encoding", func(t *ldtest.T) {
+				stream, _ := newCompressedStream(t)
+				header := stream.RequestInfo.Headers.Get("Accept-Encoding")
+				assert.True(t, acceptsEncoding(header, encoding),
+					"Accept-Encoding header %q does not allow %q", header, encoding)
+			})
+
+			t.Run("events arrive promptly", func(t *ldtest.T) {
+				stream, client := newCompressedStream(t)
+				for _, data := range []string{"first", "second", "third"} {
+					stream.Send("data: " + data + "\n\n")
+					m, err := client.AwaitMessage(compressedEventTimeout)
+					require.NoError(t, err, "event %q was not received promptly", data)
+					require.NotNil(t, m.Event, "expected an event but got: %s", m)
+					assertEventMatches(t, EventMessage{Data: data}, *m.Event)
+				}
+			})
+
+			t.Run("events are intact", func(t *ldtest.T) {
+				stream, client := newCompressedStream(t)
+				client.BePreparedToReceiveEventType(t, "greeting")
+				stream.Send("event: greeting\nid: abc\ndata: €豆腐\ndata: second line\n\ndata: Hello\n\n")
+				client.RequireSpecificEvents(t,
+					EventMessage{Type: "greeting", ID: "abc", Data: "€豆腐\nsecond line"},
+					EventMessage{ID: "abc", Data: "Hello"},
+				)
+			})
+
+			t.Run("event split across compressed blocks", func(t *ldtest.T) {
+				stream, client := newCompressedStream(t)
+				stream.SendInChunks("data: Hello\ndata: World\n\n", 3, scenarioChunkDelay)
+				client.RequireSpecificEvents(t, EventMessage{Data: "Hello\nWorld"})
+			})
+
+			t.Run("large event", func(t *ldtest.T) {
+				stream, client := newCompressedStream(t)
+				randomData := generateRandomString(1024 * 1024)
+				stream.Send("data: " + randomData + "\n\n")
+				actual := client.RequireEvent(t)
+				// Does not use RequireSpecificEvents, because then it would print megabytes of text.
+				if actual.Data != randomData {
+					assert.Fail(t, "Random message data did not match.")
+				}
+			})
+		})
+	}
+}
+
+// acceptsEncoding returns true if an Accept-Encoding header value allows the specified encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, item := range strings.Split(header, ",") {
+		params := strings.Split(item, ";")
+		name := strings.TrimSpace(params[0])
+		if !strings.EqualFold(name, encoding) && name != "*" {
+			continue
+		}
+		for _, p := range params[1:] {
+			kv := strings.SplitN(p, "=", 2)
+			if len(kv) == 2 && strings.TrimSpace(kv[0]) == "q" {
+				if q, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64); err == nil && q == 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package ssetests
+
+import (
+	"net/http"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/launchdarkly/go-test-helpers/v2/httphelpers"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+func DoConnectionStateTests(t *ldtest.T) {
+	t.RequireCapability("connection-state")
+
+	params := servicedef.CreateStreamParams{
+		InitialDelayMS:        ldvalue.NewOptionalInt(0),
+		ReportConnectionState: true,
+	}
+
+	t.Run("opened after successful connection", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		client.RequireOpened(t)
+
+		stream.Send("data: hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "hello"})
+	})
+
+	t.Run("not opened after HTTP error status", func(t *ldtest.T) {
+		handler := httphelpers.HandlerWithStatus(500)
+		endpoint := requireContext(t).harness.NewMockEndpoint(handler, nil, t.DebugLogger())
+		t.Defer(endpoint.Close)
+
+		p := params
+		p.StreamURL = endpoint.BaseURL()
+		client := NewSSEClient(t, WithClientParams(p))
+
+		client.RequireError(t)
+	})
+
+	t.Run("not opened with wrong content type", func(t *ldtest.T) {
+		headers := make(http.Header)
+		headers.Set("Content-Type", "text/plain")
+		handler := httphelpers.HandlerWithResponse(200, headers, []byte("data: hello\n\n"))
+		endpoint := requireContext(t).harness.NewMockEndpoint(handler, nil, t.DebugLogger())
+		t.Defer(endpoint.Close)
+
+		p := params
+		p.StreamURL = endpoint.BaseURL()
+		client := NewSSEClient(t, WithClientParams(p))
+
+		client.RequireError(t)
+	})
+
+	t.Run("closed after connection is broken", func(t *ldtest.T) {
+		server, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params))
+		client.RequireOpened(t)
+
+		stream1.BreakConnection()
+		client.RequireClosed(t)
+		client.IgnoreErrorHere()
+
+		stream2 := server.AwaitConnection(t)
+		client.RequireOpened(t)
+
+		stream2.Send("data: hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "hello"})
+	})
+}
@@ -0,0 +1,72 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// maxDispatchLatency is how long we allow between the stream server sending the end of an event
+// and the test harness hearing about it from the test service. This includes the time it takes
+// for the test service to send us a callback, so it is much longer than an SSE client should need.
+const maxDispatchLatency = time.Millisecond * 250
+
+// DoDispatchLatencyTests verifies that the SSE client dispatches each event as soon as it has
+// received the blank line that ends it, rather than waiting for more data to arrive. In these
+// tests, nothing else is sent on the stream after the last event, so a client that buffers
+// complete events until the next chunk, or that waits to see whether a CR is followed by an LF
+// before ending the line, does not deliver them at all.
+func DoDispatchLatencyTests(t *ldtest.T) {
+	params := servicedef.CreateStreamParams{
+		InitialDelayMS: ldvalue.NewOptionalInt(0),
+	}
+
+	t.Run("event is dispatched as soon as it is complete", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		sentAt := stream.SendAndWait(t, "data: Hello\n\n")
+		assertEventMatches(t, EventMessage{Data: "Hello"}, client.RequireEventWithin(t, sentAt, maxDispatchLatency))
+	})
+
+	for _, p := range []struct{ name, eol string }{
+		{"LF", "\n"},
+		{"CR", "\r"},
+		{"CRLF", "\r\n"},
+	} {
+		eol := p.eol
+		t.Run("event ending in "+p.name+" is dispatched as soon as it is complete", func(t *ldtest.T) {
+			_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+			sentAt := stream.SendAndWait(t, "data: Hello"+eol+eol)
+			assertEventMatches(t, EventMessage{Data: "Hello"}, client.RequireEventWithin(t, sentAt, maxDispatchLatency))
+		})
+	}
+
+	t.Run("event completed by a later chunk", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		// The final chunk is queued behind the others, so it is sent after their delays.
+		stream.SendInChunks("data: Hello\n", 4, time.Millisecond*50)
+		sentAt := stream.SendAndWait(t, "\n")
+		assertEventMatches(t, EventMessage{Data: "Hello"}, client.RequireEventWithin(t, sentAt, maxDispatchLatency))
+	})
+
+	t.Run("several events in one chunk", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		sentAt := stream.SendAndWait(t, "data: one\n\ndata: two\n\ndata: three\n\n")
+		for _, data := range []string{"one", "two", "three"} {
+			assertEventMatches(t, EventMessage{Data: data}, client.RequireEventWithin(t, sentAt, maxDispatchLatency))
+		}
+	})
+
+	t.Run("complete event followed by partial event in the same chunk", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		sentAt := stream.SendAndWait(t, "data: Hello\n\ndata: Partial")
+		assertEventMatches(t, EventMessage{Data: "Hello"}, client.RequireEventWithin(t, sentAt, maxDispatchLatency))
+	})
+}
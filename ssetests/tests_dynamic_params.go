@@ -0,0 +1,88 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// DoDynamicRequestParamsTests verifies that the caller can change the request headers or the stream
+// URL of an existing client, for instance to refresh an auth token, and that the client uses the new
+// values when it next reconnects.
+func DoDynamicRequestParamsTests(t *ldtest.T) {
+	t.RequireCapability("dynamic-request-params")
+
+	t.Run("new headers are used on reconnect", func(t *ldtest.T) {
+		t.RequireCapability("headers")
+
+		params := servicedef.CreateStreamParams{
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+			Headers:        map[string]string{"Authorization": "token1", "X-Old-Header": "old"},
+		}
+		server, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params))
+		assert.Equal(t, "token1", stream1.RequestInfo.Headers.Get("Authorization"))
+
+		stream1.Send("id: abc\ndata: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{ID: "abc", Data: "Hello"})
+
+		client.SetHeaders(t, map[string]string{"Authorization": "token2", "X-New-Header": "new"})
+		stream1.BreakConnection()
+		client.IgnoreErrorHere()
+
+		stream2 := server.AwaitConnection(t)
+		assert.Equal(t, "token2", stream2.RequestInfo.Headers.Get("Authorization"),
+			"client did not use new header value after reconnecting")
+		assert.Equal(t, "new", stream2.RequestInfo.Headers.Get("X-New-Header"),
+			"client did not add new header after reconnecting")
+		assert.Empty(t, stream2.RequestInfo.Headers.Values("X-Old-Header"),
+			"client kept a custom header that was not in the new set")
+		assert.Equal(t, "abc", stream2.RequestInfo.Headers.Get("Last-Event-Id"),
+			"client should still send Last-Event-Id along with the new headers")
+
+		stream2.Send("id: def\ndata: World\n\n")
+		client.RequireSpecificEvents(t, EventMessage{ID: "def", Data: "World"})
+	})
+
+	t.Run("new URL is used on reconnect", func(t *ldtest.T) {
+		const newRelativeURI = "/new/path?token=2"
+		params := servicedef.CreateStreamParams{
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+		}
+		server1, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params))
+		server2 := NewStreamServer(t)
+
+		stream1.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+		client.SetURL(t, server2.URLWithPath(newRelativeURI))
+		stream1.BreakConnection()
+		client.IgnoreErrorHere()
+
+		stream2 := server2.AwaitConnection(t)
+		assert.Equal(t, newRelativeURI, stream2.RequestInfo.RequestURI, "incorrect path or query for new URL")
+		stream2.Send("data: World\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "World"})
+
+		server1.RequireNoConnection(t, time.Millisecond*200)
+	})
+
+	t.Run("changing parameters does not interrupt current stream", func(t *ldtest.T) {
+		t.RequireCapability("headers")
+
+		server, stream, client := NewStreamAndSSEClient(t)
+		server2 := NewStreamServer(t)
+
+		client.SetHeaders(t, map[string]string{"Authorization": "token2"})
+		client.SetURL(t, server2.URLWithPath(""))
+
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+		server.RequireNoConnection(t, time.Millisecond*200)
+		server2.RequireNoConnection(t, time.Millisecond*200)
+	})
+}
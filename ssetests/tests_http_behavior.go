@@ -9,6 +9,7 @@ import (
 	"github.com/launchdarkly/sse-contract-tests/servicedef"
 
 	"github.com/launchdarkly/go-test-helpers/v2/httphelpers"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -43,6 +44,25 @@ func DoHTTPBehaviorTests(t *ldtest.T) {
 		})
 	}
 
+	t.Run("error status is reported", func(t *ldtest.T) {
+		h := httphelpers.HandlerWithStatus(500)
+		endpointReturning500 := requireContext(t).harness.NewMockEndpoint(h, nil, t.DebugLogger())
+		t.Defer(endpointReturning500.Close)
+
+		client := NewSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+			StreamURL:      endpointReturning500.BaseURL(),
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+		}))
+
+		m := client.RequireErrorOfCategory(t, servicedef.ErrorCategoryHTTPStatus)
+		if t.Capabilities().Has("error-categories") {
+			assert.Equal(t, 500, m.HTTPStatus)
+			if m.WillRetry != nil {
+				assert.True(t, *m.WillRetry, "client said it would not retry after HTTP 500")
+			}
+		}
+	})
+
 	for _, status := range []int{301, 307} {
 		t.Run(fmt.Sprintf("client follows %d redirect", status), func(t *ldtest.T) {
 			server := NewStreamServer(t)
@@ -80,7 +100,10 @@ func DoHTTPBehaviorTests(t *ldtest.T) {
 					StreamURL: endpointReturningRedirect.BaseURL(),
 				}))
 
-				client.RequireError(t)
+				m := client.RequireErrorOfCategory(t, servicedef.ErrorCategoryRedirect)
+				if m.HTTPStatus != 0 {
+					assert.Equal(t, status, m.HTTPStatus)
+				}
 			})
 		}
 	}
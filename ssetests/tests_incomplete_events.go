@@ -0,0 +1,62 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// DoIncompleteEventTests verifies that an event which has not been terminated by a blank line when
+// the stream ends is discarded, as the SSE specification requires: "Once the end of the file is
+// reached, any pending data must be discarded. (If the file ends in the middle of an event, before
+// the final empty line, the incomplete event is not dispatched.)"
+func DoIncompleteEventTests(t *ldtest.T) {
+	tails := []struct {
+		name string
+		data string
+	}{
+		{"trailing data line", "data: Partial\n"},
+		{"trailing data without line ending", "data: Partial"},
+		{"trailing id line", "id: abc\n"},
+		{"trailing data and id lines", "data: Partial\nid: abc\n"},
+		{"trailing event type line", "event: greeting\ndata: Partial\n"},
+		{"CR with no LF", "data: Partial\r"},
+	}
+
+	for _, tail := range tails {
+		tail := tail
+		t.Run(tail.name, func(t *ldtest.T) {
+			params := servicedef.CreateStreamParams{
+				InitialDelayMS: ldvalue.NewOptionalInt(0),
+			}
+			server, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params))
+			client.BePreparedToReceiveEventType(t, "greeting")
+
+			stream1.Send("data: Complete\n\n" + tail.data)
+			client.RequireSpecificEvents(t, EventMessage{Data: "Complete"})
+
+			stream1.BreakConnection()
+			client.IgnoreErrorHere() // client may or may not signal an error; we only care about the events here
+
+			// A client that dispatches the incomplete event when the stream ends would do so right away,
+			// before it has any data from the next connection.
+			client.RequireNoEvent(t, time.Millisecond*500)
+
+			// If the incomplete event was dispatched late, it will be received before this one.
+			stream2 := server.AwaitConnection(t)
+			stream2.Send("data: Next\n\n")
+
+			e := client.RequireEvent(t)
+			assert.Equal(t, "Next", e.Data)
+			assert.NotEqual(t, "greeting", e.Type)
+			// As in "discards partial messages on retry", we only check that the ID from the incomplete
+			// event was not picked up, rather than what the ID should be.
+			assert.NotEqual(t, "abc", e.ID)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func DoProxyTests(t *ldtest.T) {
+	t.RequireCapability("proxy")
+
+	credentials := harness.ProxyOptions{Username: "proxyuser", Password: "proxypass"}
+
+	t.Run("stream goes through proxy", func(t *ldtest.T) {
+		proxy := NewProxyServer(t, harness.ProxyOptions{})
+		server, stream, client := NewStreamAndSSEClient(t, proxy)
+		proxy.RequireRequestTo(t, server)
+
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+
+	t.Run("proxy credentials are sent", func(t *ldtest.T) {
+		proxy := NewProxyServer(t, credentials)
+		server, stream, client := NewStreamAndSSEClient(t, proxy)
+		proxy.RequireRequestTo(t, server)
+
+		assert.Empty(t, stream.RequestInfo.Headers.Values("Proxy-Authorization"),
+			"proxy credentials should not be passed on to the stream server")
+
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+
+	t.Run("rejected proxy credentials are reported as an error", func(t *ldtest.T) {
+		proxy := NewProxyServer(t, credentials)
+		server := NewStreamServer(t)
+		client := NewSSEClient(t,
+			WithClientParams(servicedef.CreateStreamParams{
+				ProxyURL: proxy.URLWithCredentials(credentials.Username, "wrong"),
+			}),
+			server,
+		)
+
+		m := client.RequireErrorOfCategory(t, servicedef.ErrorCategoryHTTPStatus)
+		if m.HTTPStatus != 0 {
+			assert.Equal(t, 407, m.HTTPStatus)
+		}
+		server.RequireNoConnection(t, time.Millisecond*500)
+	})
+
+	t.Run("reconnection goes through proxy", func(t *ldtest.T) {
+		params := servicedef.CreateStreamParams{
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+		}
+		proxy := NewProxyServer(t, credentials)
+		server, stream1, client := NewStreamAndSSEClient(t, WithClientParams(params), proxy)
+		proxy.RequireRequestTo(t, server)
+
+		stream1.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+		stream1.BreakConnection()
+		client.IgnoreErrorHere() // client may or may not signal an error; we only care about the events here
+
+		stream2 := server.AwaitConnection(t)
+		proxy.RequireRequestTo(t, server)
+
+		stream2.Send("data: World\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "World"})
+	})
+}
@@ -0,0 +1,76 @@
+package ssetests
+
+import (
+	"strings"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// DoReadTimeoutTests verifies how the read timeout interacts with data that arrives while the
+// stream is otherwise idle. As described for the "read-timeout" capability in
+// docs/optional_features.md, a server can send heartbeats, such as empty comments, more often than
+// the timeout in order to keep the connection open, and receiving any bytes at all should reset
+// the timeout clock.
+func DoReadTimeoutTests(t *ldtest.T) {
+	t.RequireCapability("read-timeout")
+
+	const (
+		readTimeout       = time.Millisecond * 500
+		heartbeatInterval = time.Millisecond * 200
+		heartbeatCount    = 10 // so the heartbeats last for more than three times the read timeout
+		slowInterval      = time.Millisecond * 700
+	)
+
+	params := servicedef.CreateStreamParams{
+		InitialDelayMS: ldvalue.NewOptionalInt(0),
+		ReadTimeoutMS:  ldvalue.NewOptionalInt(int(readTimeout / time.Millisecond)),
+	}
+
+	for _, p := range []struct{ name, heartbeat string }{
+		{"empty comment", ":\n"},
+		{"comment", ": keep-alive\n"},
+		{"blank line", "\n"},
+	} {
+		heartbeat := p.heartbeat
+		t.Run(p.name+" heartbeat resets read timeout", func(t *ldtest.T) {
+			server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+			client.IgnoreComments()
+
+			stream.SendInChunks(strings.Repeat(heartbeat, heartbeatCount), len(heartbeat), heartbeatInterval)
+			stream.Send("data: Hello\n\n")
+
+			// If the client timed out during the heartbeats, it would report an error before the event.
+			client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+			server.RequireNoConnection(t, time.Millisecond*100)
+		})
+	}
+
+	t.Run("partial line resets read timeout", func(t *ldtest.T) {
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+
+		// Each chunk is only a few bytes, so there is no line ending until the very end.
+		data := "data: Hello, world\n\n"
+		chunkSize := (len(data) + heartbeatCount - 1) / heartbeatCount
+		stream.SendInChunks(data, chunkSize, heartbeatInterval)
+
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello, world"})
+		server.RequireNoConnection(t, time.Millisecond*100)
+	})
+
+	t.Run("heartbeats slower than read timeout do not prevent it", func(t *ldtest.T) {
+		server, stream, client := NewStreamAndSSEClient(t, WithClientParams(params))
+		client.IgnoreComments()
+
+		stream.SendInChunks(strings.Repeat(":\n", 3), 2, slowInterval)
+
+		client.RequireErrorOfCategory(t, servicedef.ErrorCategoryTimeout)
+		stream2 := server.AwaitConnection(t)
+
+		stream2.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+}
@@ -146,7 +146,7 @@ func DoReconnectionTests(t *ldtest.T) {
 
 		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
 
-		client.RequireError(t)
+		client.RequireErrorOfCategory(t, servicedef.ErrorCategoryTimeout)
 
 		server.AwaitConnection(t)
 	})
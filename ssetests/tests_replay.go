@@ -0,0 +1,94 @@
+package ssetests
+
+import (
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// maxReplayDelay limits the delay between replayed chunks. A real stream can be idle for much
+// longer than we are willing to wait for an event, and long pauses do not matter to a parser
+// unless it has a read timeout, which replays do not use.
+const maxReplayDelay = time.Second
+
+// replayNoExtraEventsTime is how long we wait, after the whole capture has been sent and all of the
+// expected events have arrived, to make sure that the client does not produce any more events.
+const replayNoExtraEventsTime = time.Millisecond * 500
+
+// RunReplaySuite runs a test for each of the specified stream captures, instead of the usual test
+// suite. Each test is called "replay/<name>".
+func RunReplaySuite(
+	harness *harness.TestHarness,
+	replays []Replay,
+	filter ldtest.Filter,
+	testLogger ldtest.TestLogger,
+) ldtest.Results {
+	config := ldtest.TestConfiguration{
+		Filter:       filter,
+		Capabilities: harness.TestServiceInfo().Capabilities,
+		TestLogger:   testLogger,
+		Context: SSETestContext{
+			harness: harness,
+		},
+	}
+
+	return ldtest.Run(config, func(t *ldtest.T) {
+		t.Run("replay", func(t *ldtest.T) {
+			for _, r := range replays {
+				t.Run(r.Name, doReplayTest(r))
+			}
+		})
+	})
+}
+
+func doReplayTest(r Replay) func(t *ldtest.T) {
+	return func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t)
+		for _, e := range r.Expected {
+			if e.Type != "" {
+				client.BePreparedToReceiveEventType(t, e.Type)
+			}
+		}
+
+		// We send the chunks from another goroutine, so that the events can be checked as they
+		// arrive; otherwise a long capture could take longer than we are willing to wait for the
+		// first event.
+		doneCh := make(chan struct{})
+		t.Defer(func() { close(doneCh) })
+		sentCh := make(chan struct{})
+		go func() {
+			defer close(sentCh)
+			for _, chunk := range r.Capture.Chunks {
+				delay := time.Duration(chunk.DelayMS) * time.Millisecond
+				if delay > maxReplayDelay {
+					delay = maxReplayDelay
+				}
+				select {
+				case <-time.After(delay):
+					stream.Send(string(chunk.Data))
+				case <-doneCh:
+					return
+				}
+			}
+		}()
+
+		for i, expected := range r.Expected {
+			m := client.RequireMessage(t)
+			for m.Kind == "comment" {
+				m = client.RequireMessage(t)
+			}
+			if m.Kind != "event" || m.Event == nil {
+				require.Fail(t, "received an unexpected message", "expected event %d but got: %s", i+1, m)
+			}
+			assertEventMatches(t, expected, *m.Event, "event %d", i+1)
+		}
+
+		// An extra event, such as one made from partial data at the end of the capture, is as much
+		// of a problem as a missing one.
+		<-sentCh
+		client.RequireNoEvent(t, replayNoExtraEventsTime)
+	}
+}
@@ -0,0 +1,85 @@
+package ssetests
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+)
+
+// DoResponseHeaderTests verifies how the client treats the headers of the stream response. The
+// SSE specification says that the client should only read the stream "if res's status is 200, and
+// its Content-Type is text/event-stream, ignoring MIME type parameters"; otherwise it must "fail
+// the connection". The stream is always decoded as UTF-8, regardless of any charset parameter.
+func DoResponseHeaderTests(t *ldtest.T) {
+	withContentType := func(contentType string) StreamServerOption {
+		return WithResponseHeaders(http.Header{"Content-Type": {contentType}})
+	}
+
+	// A failed connection means the client reports an error, does not read anything from the
+	// response, and does not reconnect. The reconnect delay is zero, so that a client that did
+	// reconnect would do so right away.
+	requireFailedConnection := func(t *ldtest.T, options ...StreamServerOption) {
+		server := NewStreamServer(t, options...)
+		client := NewSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+		}), server)
+		stream := server.AwaitConnection(t)
+		stream.Send("data: Hello\n\n")
+		client.RequireErrorOfCategory(t, servicedef.ErrorCategoryInvalidContentType)
+		client.RequireNoEvent(t, time.Millisecond*500)
+		server.RequireNoConnection(t, time.Millisecond*100)
+	}
+
+	for _, contentType := range []string{
+		"text/event-stream",
+		"TEXT/EVENT-STREAM",
+		"Text/Event-Stream; Charset=UTF-8",
+		"text/event-stream;charset=utf-8",
+		"text/event-stream; charset=utf-8; extra=value",
+		"text/event-stream ; charset=utf-8",
+	} {
+		contentType := contentType
+		t.Run("accepts Content-Type "+contentType, func(t *ldtest.T) {
+			server := NewStreamServer(t, withContentType(contentType))
+			client := NewSSEClient(t, server)
+			stream := server.AwaitConnection(t)
+			stream.Send("data: Hello\n\n")
+			client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+		})
+	}
+
+	t.Run("decodes as UTF-8 despite a different charset", func(t *ldtest.T) {
+		// In ISO-8859-1, these bytes would be three separate characters.
+		server := NewStreamServer(t, withContentType("text/event-stream; charset=iso-8859-1"))
+		client := NewSSEClient(t, server)
+		stream := server.AwaitConnection(t)
+		stream.Send("data: €\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "€"})
+	})
+
+	t.Run("fails connection with missing Content-Type", func(t *ldtest.T) {
+		requireFailedConnection(t, WithResponseHeaders(http.Header{"Content-Type": nil}))
+	})
+
+	for _, contentType := range []string{"application/json", "text/plain", "text/event-stream-extra"} {
+		contentType := contentType
+		t.Run("fails connection with Content-Type "+contentType, func(t *ldtest.T) {
+			requireFailedConnection(t, withContentType(contentType))
+		})
+	}
+
+	t.Run("ignores unusual cache headers", func(t *ldtest.T) {
+		server := NewStreamServer(t, WithResponseHeaders(http.Header{
+			"Cache-Control": {"public, max-age=3600"},
+			"Expires":       {"Thu, 01 Jan 2099 00:00:00 GMT"},
+		}))
+		client := NewSSEClient(t, server)
+		stream := server.AwaitConnection(t)
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+}
@@ -0,0 +1,107 @@
+package ssetests
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/launchdarkly/go-test-helpers/v2/httphelpers"
+	"gopkg.in/launchdarkly/go-sdk-common.v2/ldvalue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DoStreamURLTests verifies that the client uses the stream URL exactly as it was given, including
+// any subpath, query string, and percent-encoding, both for the first request and for reconnects.
+func DoStreamURLTests(t *ldtest.T) {
+	relativeURIs := []string{
+		"/sub/path",
+		"/sub/path/",
+		"?filter=abc",
+		"/sub/path?filter=abc&flag",
+		"/sub/a%20b?q=x%20y&amp=%26&eq=%3D&euro=%E2%82%AC",
+	}
+
+	for _, relativeURI := range relativeURIs {
+		relativeURI := relativeURI
+		t.Run("stream URL "+relativeURI, func(t *ldtest.T) {
+			server := NewStreamServer(t)
+			client := NewSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+				StreamURL:      server.URLWithPath(relativeURI),
+				InitialDelayMS: ldvalue.NewOptionalInt(0),
+			}))
+
+			stream1 := server.AwaitConnection(t)
+			assert.Equal(t, relativeURI, stream1.RequestInfo.RequestURI, "incorrect path or query in first request")
+			stream1.Send("data: Hello\n\n")
+			client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+			stream1.BreakConnection()
+			client.IgnoreErrorHere()
+
+			stream2 := server.AwaitConnection(t)
+			assert.Equal(t, relativeURI, stream2.RequestInfo.RequestURI, "incorrect path or query after reconnecting")
+			stream2.Send("data: World\n\n")
+			client.RequireSpecificEvents(t, EventMessage{Data: "World"})
+		})
+	}
+
+	newRedirectEndpoint := func(t *ldtest.T, location string) *harness.MockEndpoint {
+		headers := make(http.Header)
+		headers.Set("Location", location)
+		handler := httphelpers.HandlerWithResponse(http.StatusTemporaryRedirect, headers, nil)
+		endpoint := requireContext(t).harness.NewMockEndpoint(handler, nil, t.DebugLogger())
+		t.Defer(endpoint.Close)
+		return endpoint
+	}
+
+	t.Run("redirect to URL with query", func(t *ldtest.T) {
+		const redirectedURI = "/redirected?token=a%20b&euro=%E2%82%AC"
+		server := NewStreamServer(t)
+		redirect := newRedirectEndpoint(t, server.URLWithPath(redirectedURI))
+
+		client := NewSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+			StreamURL:      redirect.BaseURL(),
+			InitialDelayMS: ldvalue.NewOptionalInt(0),
+		}))
+
+		stream1 := server.AwaitConnection(t)
+		assert.Equal(t, redirectedURI, stream1.RequestInfo.RequestURI, "incorrect path or query after redirect")
+		stream1.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+
+		// The client can either reconnect to the original URL, and be redirected again, or go straight
+		// to the URL it was redirected to; either way, the query must be intact.
+		stream1.BreakConnection()
+		client.IgnoreErrorHere()
+
+		stream2 := server.AwaitConnection(t)
+		assert.Equal(t, redirectedURI, stream2.RequestInfo.RequestURI, "incorrect path or query after reconnecting")
+		stream2.Send("data: World\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "World"})
+	})
+
+	t.Run("redirect from URL with query", func(t *ldtest.T) {
+		const originalURI = "?token=a%20b"
+		server := NewStreamServer(t)
+		redirect := newRedirectEndpoint(t, server.URLWithPath("/redirected"))
+
+		client := NewSSEClient(t, WithClientParams(servicedef.CreateStreamParams{
+			StreamURL: redirect.BaseURL() + originalURI,
+		}))
+
+		redirectRequest, err := redirect.AwaitConnection(time.Second * 5)
+		require.NoError(t, err)
+		assert.Equal(t, originalURI, redirectRequest.RequestURI, "incorrect path or query before redirect")
+
+		stream := server.AwaitConnection(t)
+		assert.Equal(t, "/redirected", stream.RequestInfo.RequestURI,
+			"client should use the Location URL as it is, without adding the original query")
+		stream.Send("data: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Data: "Hello"})
+	})
+}
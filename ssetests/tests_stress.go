@@ -0,0 +1,219 @@
+package ssetests
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// DefaultStressEventCount is the default value of StressOptions.EventCount.
+	DefaultStressEventCount = 100000
+
+	// DefaultStressEventSize is the default value of StressOptions.EventSize.
+	DefaultStressEventSize = 100
+
+	// stressBatchSize is the approximate number of bytes that we send on the stream at a time.
+	stressBatchSize = 64 * 1024
+
+	// stressWindowSize is how many bytes we allow to be sent but not yet received as events. Without
+	// this limit, the stream server would queue up far more data than the client can handle, and
+	// the latency would mostly be a measure of how long the data sat in the queue.
+	stressWindowSize = 16 * stressBatchSize
+
+	// stressStallTimeout is how long we wait for the next event before deciding that the client
+	// is not going to deliver any more.
+	stressStallTimeout = time.Second * 10
+)
+
+// StressOptions contains parameters for RunStressSuite.
+type StressOptions struct {
+	// EventCount is the number of events to send in each test. If it is zero,
+	// DefaultStressEventCount is used.
+	EventCount int
+
+	// EventSize is the number of bytes of data in each event. If it is zero, DefaultStressEventSize
+	// is used. Each event's data starts with its sequence number, so the data may be slightly larger
+	// than this if EventSize is very small.
+	EventSize int
+}
+
+// RunStressSuite runs tests that push a large volume of events through the SSE client, instead of
+// the usual test suite. Each test fails if any event is dropped, duplicated, reordered, or
+// corrupted, and reports the throughput that it measured, as well as the delivery latency. Each
+// test is called "stress/<name>".
+func RunStressSuite(
+	harness *harness.TestHarness,
+	options StressOptions,
+	filter ldtest.Filter,
+	testLogger ldtest.TestLogger,
+) ldtest.Results {
+	if options.EventCount <= 0 {
+		options.EventCount = DefaultStressEventCount
+	}
+	if options.EventSize <= 0 {
+		options.EventSize = DefaultStressEventSize
+	}
+	config := ldtest.TestConfiguration{
+		Filter:       filter,
+		Capabilities: harness.TestServiceInfo().Capabilities,
+		TestLogger:   testLogger,
+		Context: SSETestContext{
+			harness: harness,
+		},
+	}
+
+	return ldtest.Run(config, func(t *ldtest.T) {
+		t.Run("stress", func(t *ldtest.T) {
+			t.Run("single-line events", func(t *ldtest.T) {
+				doStressTest(t, options, 1)
+			})
+			t.Run("multi-line events", func(t *ldtest.T) {
+				doStressTest(t, options, 10)
+			})
+		})
+	})
+}
+
+// stressEventData returns the data of the event with the specified sequence number. The sequence
+// number comes first, so that the test can tell which event it is even if the rest is corrupted.
+func stressEventData(seq, size, lines int) string {
+	prefix := strconv.Itoa(seq) + ":"
+	fillerSize := size - len(prefix)
+	if fillerSize < 0 {
+		fillerSize = 0
+	}
+	var b strings.Builder
+	b.Grow(len(prefix) + fillerSize + lines)
+	b.WriteString(prefix)
+	lineLength := fillerSize/lines + 1
+	for i := 0; i < fillerSize; i++ {
+		if i > 0 && i%lineLength == 0 {
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteByte(byte('a' + (seq+i)%26))
+	}
+	return b.String()
+}
+
+func stressEventText(data string) string {
+	return "data: " + strings.ReplaceAll(data, "\n", "\ndata: ") + "\n\n"
+}
+
+// stressStats accumulates what we know about the events the client has delivered.
+type stressStats struct {
+	received   int
+	dropped    int
+	duplicated int
+	reordered  int
+	corrupted  int
+	bytes      int64
+}
+
+func doStressTest(t *ldtest.T, options StressOptions, lines int) {
+	server := NewStreamServer(t, WithoutDataLogging())
+	client := NewSSEClient(t, server)
+	client.DisableMessageLogging()
+	stream := server.AwaitConnection(t)
+
+	count := options.EventCount
+	var receivedBytes int64 // total size of the events received so far, read atomically
+	doneCh := make(chan struct{})
+	t.Defer(func() { close(doneCh) })
+
+	start := time.Now()
+	go func() {
+		var sentBytes int64
+		var batch strings.Builder
+		for seq := 0; seq < count; {
+			for sentBytes-atomic.LoadInt64(&receivedBytes) > stressWindowSize {
+				select {
+				case <-doneCh:
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+			batch.Reset()
+			for ; seq < count && batch.Len() < stressBatchSize; seq++ {
+				batch.WriteString(stressEventText(stressEventData(seq, options.EventSize, lines)))
+			}
+			sentBytes += int64(batch.Len())
+			stream.Send(batch.String())
+		}
+	}()
+
+	var stats stressStats
+	seen := make([]bool, count)
+	next := 0
+	for next < count {
+		m, err := client.AwaitMessage(stressStallTimeout)
+		if err == errMessageTimeout {
+			t.Debug("Stopped waiting after receiving %d of %d events", stats.received, count)
+			break // any events that we never got are counted as dropped below
+		}
+		require.NoError(t, err)
+		if m.Kind != servicedef.CallbackKindEvent || m.Event == nil {
+			require.Fail(t, "received an unexpected message", "expected an event but got: %s", m)
+		}
+		stats.received++
+		data := m.Event.Data
+		seq, err := strconv.Atoi(data[:strings.IndexByte(data+":", ':')])
+		if err != nil || seq < 0 || seq >= count {
+			stats.corrupted++
+			continue
+		}
+		switch {
+		case seen[seq]:
+			stats.duplicated++
+			continue
+		case seq > next:
+			stats.dropped += seq - next
+		case seq < next:
+			// This one arrived after later events, so we counted it as dropped too soon.
+			stats.reordered++
+			stats.dropped--
+		}
+		seen[seq] = true
+		atomic.AddInt64(&receivedBytes, int64(len(stressEventText(data))))
+		stats.bytes += int64(len(data))
+		if seq >= next {
+			next = seq + 1
+		}
+		if data != stressEventData(seq, options.EventSize, lines) {
+			stats.corrupted++
+		}
+	}
+	elapsed := time.Since(start)
+	stats.dropped += count - next
+
+	// The SSE client reports the latency distribution itself, at the end of the test.
+	t.Report("%s", stats.describe(elapsed))
+	assert.Zero(t, stats.dropped, "events were dropped")
+	assert.Zero(t, stats.duplicated, "events were delivered more than once")
+	assert.Zero(t, stats.reordered, "events were delivered out of order")
+	assert.Zero(t, stats.corrupted, "events had incorrect data")
+}
+
+func (s stressStats) describe(elapsed time.Duration) string {
+	seconds := elapsed.Seconds()
+	lines := []string{
+		fmt.Sprintf("received %d events (%.1f MB) in %s: %.0f events/sec, %.2f MB/sec",
+			s.received, float64(s.bytes)/1e6, elapsed.Round(time.Millisecond),
+			float64(s.received)/seconds, float64(s.bytes)/1e6/seconds),
+	}
+	if s.dropped != 0 || s.duplicated != 0 || s.reordered != 0 || s.corrupted != 0 {
+		lines = append(lines, fmt.Sprintf("dropped: %d, duplicated: %d, reordered: %d, corrupted: %d",
+			s.dropped, s.duplicated, s.reordered, s.corrupted))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,68 @@
+package ssetests
+
+import (
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+)
+
+// DoUTF8DecodingTests verifies that the stream is decoded with the UTF-8 decode algorithm, as the
+// SSE specification requires. In that algorithm, which is defined in the WHATWG Encoding standard
+// (https://encoding.spec.whatwg.org/#utf-8-decoder), each maximal subsequence of bytes that cannot
+// begin a valid character is replaced by a single U+FFFD; it is not an error, and it does not stop
+// the stream.
+//
+// The test service must pass the decoded text to us in valid JSON, so these tests also check that
+// it does not send invalid UTF-8 in its callbacks.
+func DoUTF8DecodingTests(t *ldtest.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{"invalid lead byte", "a\xFFb", "a\uFFFDb"},
+		{"unexpected continuation byte", "a\x80b", "a\uFFFDb"},
+		{"lead byte followed by non-continuation byte", "a\xC3(b", "a\uFFFD(b"},
+		{"truncated 3-byte sequence", "a\xE2\x82b", "a\uFFFDb"},
+		{"truncated 4-byte sequence", "a\xF0\x9F\x98b", "a\uFFFDb"},
+		{"truncated sequence at end of line", "a\xE2\x82", "a\uFFFD"},
+		{"overlong encoding of slash", "a\xC0\xAFb", "a\uFFFD\uFFFDb"},
+		{"overlong 3-byte encoding", "a\xE0\x80\xAFb", "a\uFFFD\uFFFD\uFFFDb"},
+		{"lone high surrogate encoded as CESU-8", "a\xED\xA0\x80b", "a\uFFFD\uFFFD\uFFFDb"},
+		{"lone low surrogate encoded as CESU-8", "a\xED\xB0\x80b", "a\uFFFD\uFFFD\uFFFDb"},
+		{"code point above U+10FFFF", "a\xF4\x90\x80\x80b", "a\uFFFD\uFFFD\uFFFD\uFFFDb"},
+		{"NUL byte", "a\x00b", "a\x00b"},
+		{"line separator U+2028 does not end a line", "a\u2028b", "a\u2028b"},
+		{"paragraph separator U+2029 does not end a line", "a\u2029b", "a\u2029b"},
+		{"next line U+0085 does not end a line", "a\u0085b", "a\u0085b"},
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *ldtest.T) {
+			_, stream, client := NewStreamAndSSEClient(t)
+			stream.Send("data: " + c.data + "\n\ndata: next\n\n")
+			client.RequireSpecificEvents(t,
+				EventMessage{Data: c.expected},
+				EventMessage{Data: "next"},
+			)
+		})
+	}
+
+	t.Run("invalid sequence in event type and ID", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t)
+		client.BePreparedToReceiveEventType(t, "gr\uFFFDeting")
+		stream.Send("event: gr\xFFeting\nid: a\xFFc\ndata: Hello\n\n")
+		client.RequireSpecificEvents(t, EventMessage{Type: "gr\uFFFDeting", ID: "a\uFFFDc", Data: "Hello"})
+	})
+
+	t.Run("truncated sequence split across chunks", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.SendInChunks("data: a\xE2\x82b\n\n", 1, scenarioChunkDelay)
+		client.RequireSpecificEvents(t, EventMessage{Data: "a\uFFFDb"})
+	})
+
+	t.Run("valid sequence split across chunks", func(t *ldtest.T) {
+		_, stream, client := NewStreamAndSSEClient(t)
+		stream.SendInChunks("data: a\xF0\x9F\x98\x80b\n\n", 1, scenarioChunkDelay)
+		client.RequireSpecificEvents(t, EventMessage{Data: "a\U0001F600b"})
+	})
+}
@@ -7,10 +7,20 @@ import (
 
 var AllCapabilities = []string{ //nolint:gochecknoglobals
 	"bom",
+	"callback-batch",
+	"callback-poll",
+	"callback-stream",
 	"comments",
+	"compression-deflate",
+	"compression-gzip",
+	"connection-state",
+	"data-base64",
+	"dynamic-request-params",
+	"error-categories",
 	"headers",
 	"last-event-id",
 	"post",
+	"proxy",
 	"read-timeout",
 	"report",
 }
@@ -34,7 +44,18 @@ func RunTestSuite(
 		t.Run("BOM handling", DoBOMTests)
 		t.Run("comments", DoCommentTests)
 		t.Run("linefeeds", DoLinefeedTests)
+		t.Run("incomplete events", DoIncompleteEventTests)
+		t.Run("dispatch latency", DoDispatchLatencyTests)
+		t.Run("UTF-8 decoding", DoUTF8DecodingTests)
 		t.Run("HTTP behavior", DoHTTPBehaviorTests)
+		t.Run("stream URL", DoStreamURLTests)
+		t.Run("response headers", DoResponseHeaderTests)
+		t.Run("compression", DoCompressionTests)
+		t.Run("proxy", DoProxyTests)
 		t.Run("reconnection", DoReconnectionTests)
+		t.Run("read timeout", DoReadTimeoutTests)
+		t.Run("client shutdown", DoClientShutdownTests)
+		t.Run("dynamic request parameters", DoDynamicRequestParamsTests)
+		t.Run("connection state", DoConnectionStateTests)
 	})
 }
@@ -0,0 +1,187 @@
+package testservice
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const (
+	callbackQueueSize    = 1000
+	maxCallbackBatchSize = 100
+)
+
+// callbackSender delivers messages to the test harness's callback endpoint for a stream. Each
+// message gets the next value of the stream's callback counter, starting at 1, which the test
+// harness uses to put the messages back in order. We also deliver them one request at a time, so
+// that the harness should rarely have to reorder anything.
+//
+// If batching is enabled, each request contains every message that was waiting when it was sent,
+// as a JSON array. If streaming is enabled, all of the messages are sent over one request instead,
+// as newline-delimited JSON, and do not need counters. If polling is enabled, the messages are not
+// sent at all, but are kept until the test harness asks for them.
+type callbackSender struct {
+	baseURL    string
+	batch      bool
+	stream     *io.PipeWriter
+	poll       bool
+	polled     []servicedef.PolledMessage
+	pollLock   sync.Mutex
+	httpClient *http.Client
+	queue      chan servicedef.CallbackMessage
+	counter    int
+	logger     framework.Logger
+	closeOnce  sync.Once
+	doneCh     chan struct{}
+}
+
+func newCallbackSender(baseURL, transport string, batch bool, logger framework.Logger) *callbackSender {
+	s := &callbackSender{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		batch:      batch,
+		httpClient: &http.Client{},
+		queue:      make(chan servicedef.CallbackMessage, callbackQueueSize),
+		logger:     logger,
+		doneCh:     make(chan struct{}),
+	}
+	switch transport {
+	case servicedef.CallbackTransportStream:
+		s.startStream()
+	case servicedef.CallbackTransportPoll:
+		s.poll = true
+	}
+	go s.run()
+	return s
+}
+
+// startStream opens the request that all messages will be sent over. The request body is a pipe,
+// which the HTTP client sends with chunked encoding as we write to it.
+func (s *callbackSender) startStream() {
+	reader, writer := io.Pipe()
+	s.stream = writer
+	go func() {
+		resp, err := s.httpClient.Post(s.baseURL+servicedef.CallbackStreamPath, "application/x-ndjson", reader)
+		if err != nil {
+			s.logger.Printf("Callback stream failed: %s", err)
+			_ = reader.CloseWithError(err)
+			return
+		}
+		_ = resp.Body.Close()
+	}()
+}
+
+// send queues a message for delivery. It blocks if the queue is full, which slows down the SSE
+// client rather than losing messages.
+func (s *callbackSender) send(message servicedef.CallbackMessage) {
+	select {
+	case s.queue <- message:
+	case <-s.doneCh:
+	}
+}
+
+// close stops delivering messages. Anything that has not yet been delivered is discarded.
+func (s *callbackSender) close() {
+	s.closeOnce.Do(func() {
+		close(s.doneCh)
+		if s.stream != nil {
+			_ = s.stream.Close()
+		}
+	})
+}
+
+func (s *callbackSender) run() {
+	for {
+		select {
+		case message := <-s.queue:
+			messages := []servicedef.CallbackMessage{message}
+			if s.batch {
+				messages = s.takeMore(messages)
+			}
+			firstCounter := s.counter + 1
+			s.counter += len(messages)
+			if err := s.post(firstCounter, messages); err != nil {
+				s.logger.Printf("Callback %d failed: %s", firstCounter, err)
+			}
+		case <-s.doneCh:
+			return
+		}
+	}
+}
+
+// takeMore adds any messages that are already waiting in the queue to a batch.
+func (s *callbackSender) takeMore(messages []servicedef.CallbackMessage) []servicedef.CallbackMessage {
+	for len(messages) < maxCallbackBatchSize {
+		select {
+		case message := <-s.queue:
+			messages = append(messages, message)
+		default:
+			return messages
+		}
+	}
+	return messages
+}
+
+// messagesAfter returns all of the messages whose counter is greater than the specified one, if
+// polling is enabled.
+func (s *callbackSender) messagesAfter(counter int) []servicedef.PolledMessage {
+	s.pollLock.Lock()
+	defer s.pollLock.Unlock()
+	ret := []servicedef.PolledMessage{}
+	for _, m := range s.polled {
+		if m.Counter > counter {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+func (s *callbackSender) post(firstCounter int, messages []servicedef.CallbackMessage) error {
+	if s.poll {
+		s.pollLock.Lock()
+		for i, m := range messages {
+			s.polled = append(s.polled, servicedef.PolledMessage{Counter: firstCounter + i, Message: m})
+		}
+		s.pollLock.Unlock()
+		return nil
+	}
+	if s.stream != nil {
+		for _, m := range messages {
+			data, err := json.Marshal(m)
+			if err != nil {
+				return err
+			}
+			if _, err := s.stream.Write(append(data, '\n')); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	var data []byte
+	var err error
+	if s.batch {
+		data, err = json.Marshal(messages)
+	} else {
+		data, err = json.Marshal(messages[0])
+	}
+	if err != nil {
+		return err
+	}
+	url := s.baseURL + "/" + strconv.Itoa(firstCounter)
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
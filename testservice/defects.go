@@ -0,0 +1,79 @@
+package testservice
+
+// Defect identifies a deliberate bug that can be introduced into the reference SSE client. A test
+// service with a defect is a "mutant": running the contract tests against it verifies that the
+// tests which are supposed to catch that kind of bug really do fail.
+type Defect string
+
+const (
+	// DefectKeepBOM makes the client treat a byte order mark at the start of the stream as data.
+	DefectKeepBOM Defect = "keep-bom"
+
+	// DefectIgnoreCR makes the client recognize only LF and CRLF as line endings, not CR by itself.
+	DefectIgnoreCR Defect = "ignore-cr"
+
+	// DefectNoLastEventID makes the client omit the Last-Event-Id header when it reconnects.
+	DefectNoLastEventID Defect = "no-last-event-id"
+
+	// DefectDispatchIncompleteEvent makes the client dispatch an event that was still incomplete
+	// when the stream ended, instead of discarding it.
+	DefectDispatchIncompleteEvent Defect = "dispatch-incomplete-event"
+
+	// DefectAcceptIDWithNull makes the client use the value of an "id:" field even if it contains
+	// a null character.
+	DefectAcceptIDWithNull Defect = "accept-id-with-null"
+
+	// DefectStripAllLeadingSpaces makes the client remove every leading space from a field value,
+	// rather than only the first one.
+	DefectStripAllLeadingSpaces Defect = "strip-all-leading-spaces"
+
+	// DefectOpenedBeforeStatusCheck makes the client report that the stream was opened as soon as
+	// it gets any response, without checking the status or content type.
+	DefectOpenedBeforeStatusCheck Defect = "opened-before-status-check"
+
+	// DefectDropQueryOnReconnect makes the client remove the query string from the stream URL when
+	// it reconnects.
+	DefectDropQueryOnReconnect Defect = "drop-query-on-reconnect"
+
+	// DefectKeepConnectionOnClose makes the client leave its current connection open when it is
+	// closed. It still stops reporting messages and does not reconnect.
+	DefectKeepConnectionOnClose Defect = "keep-connection-on-close"
+
+	// DefectTimeoutResetOnlyByLines makes the client reset its read timeout only when it receives a
+	// line ending, rather than whenever it receives any data.
+	DefectTimeoutResetOnlyByLines Defect = "timeout-reset-only-by-lines"
+
+	// DefectDispatchOnNextChunk makes the client hold each complete event until the next chunk of
+	// data arrives, as a client that waits to fill a read buffer might.
+	DefectDispatchOnNextChunk Defect = "dispatch-on-next-chunk"
+
+	// DefectIgnoreContentType makes the client report an invalid Content-Type as an error, but then
+	// read the stream anyway.
+	DefectIgnoreContentType Defect = "ignore-content-type"
+)
+
+// AllDefects is the list of every defect that the reference SSE client can be given.
+var AllDefects = []Defect{ //nolint:gochecknoglobals
+	DefectKeepBOM,
+	DefectIgnoreCR,
+	DefectNoLastEventID,
+	DefectDispatchIncompleteEvent,
+	DefectAcceptIDWithNull,
+	DefectStripAllLeadingSpaces,
+	DefectOpenedBeforeStatusCheck,
+	DefectDropQueryOnReconnect,
+	DefectKeepConnectionOnClose,
+	DefectTimeoutResetOnlyByLines,
+	DefectDispatchOnNextChunk,
+	DefectIgnoreContentType,
+}
+
+// DefectAlwaysGzip makes the reference SSE server compress every response with gzip, even if the
+// client did not ask for compression. Unlike the other defects, this one is for the server-side
+// test service.
+const DefectAlwaysGzip Defect = "always-gzip"
+
+// AllServerDefects is the list of every defect that the reference SSE server can be given.
+var AllServerDefects = []Defect{ //nolint:gochecknoglobals
+	DefectAlwaysGzip,
+}
@@ -0,0 +1,126 @@
+package testservice
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expectedMutantFailures lists, for each defect, contract tests that exist specifically to catch
+// that kind of bug. A test that passed anyway would be one that does not really verify what it
+// claims to. Every failing test has to wait for a timeout, so we only run a few for each defect.
+var expectedMutantFailures = map[Defect][]ldtest.TestID{ //nolint:gochecknoglobals
+	DefectKeepBOM: {
+		{"BOM handling", "BOM at start of stream is stripped"},
+		{"BOM handling", "BOM split byte by byte across chunks"},
+	},
+	DefectIgnoreCR: {
+		{"linefeeds", "CR separator", "one-line event", "one chunk"},
+		{"linefeeds", "CR separator", "ignores 1 extra empty line", "1-character chunks"},
+	},
+	DefectNoLastEventID: {
+		{"reconnection", "sends ID of last received event"},
+		{"reconnection", "sends ID of last received event that had an ID if later events did not"},
+	},
+	DefectDispatchIncompleteEvent: {
+		{"reconnection", "discards partial messages on retry"},
+		{"basic parsing", "event without trailing blank line is not dispatched"},
+		{"incomplete events", "trailing data line"},
+		{"incomplete events", "CR with no LF"},
+	},
+	DefectAcceptIDWithNull: {
+		{"basic parsing", "ID field is ignored if it contains a null"},
+	},
+	DefectStripAllLeadingSpaces: {
+		{"basic parsing", "fields with extra leading space"},
+		{"basic parsing", "data value that is only two spaces"},
+	},
+	DefectOpenedBeforeStatusCheck: {
+		{"connection state", "not opened after HTTP error status"},
+		{"connection state", "not opened with wrong content type"},
+	},
+	DefectDropQueryOnReconnect: {
+		{"stream URL", "stream URL ?filter=abc"},
+		{"stream URL", "stream URL /sub/path?filter=abc&flag"},
+	},
+	DefectKeepConnectionOnClose: {
+		{"client shutdown", "closing client drops connection"},
+		{"client shutdown", "closing client before any events drops connection"},
+	},
+	DefectTimeoutResetOnlyByLines: {
+		{"read timeout", "partial line resets read timeout"},
+	},
+	DefectDispatchOnNextChunk: {
+		{"dispatch latency", "event is dispatched as soon as it is complete"},
+		{"dispatch latency", "several events in one chunk"},
+	},
+	DefectIgnoreContentType: {
+		{"response headers", "fails connection with missing Content-Type"},
+		{"response headers", "fails connection with Content-Type text/plain"},
+	},
+}
+
+// expectedServerMutantFailures is the same as expectedMutantFailures, for the server-side test suite.
+var expectedServerMutantFailures = map[Defect][]ldtest.TestID{ //nolint:gochecknoglobals
+	DefectAlwaysGzip: {
+		{"HTTP behavior", "response is not compressed unless requested"},
+	},
+}
+
+func exactTestIDFilter(ids []ldtest.TestID) ldtest.Filter {
+	var filters ldtest.RegexFilters
+	for _, id := range ids {
+		var pattern ldtest.TestIDPattern
+		for _, name := range id {
+			pattern = append(pattern, regexp.MustCompile("^"+regexp.QuoteMeta(name)+"$"))
+		}
+		filters.MustMatch = append(filters.MustMatch, pattern)
+	}
+	return filters.Match
+}
+
+func TestEveryDefectHasExpectedFailures(t *testing.T) {
+	for _, d := range AllDefects {
+		assert.NotEmpty(t, expectedMutantFailures[d], "no expected failures defined for defect %q", d)
+	}
+	for _, d := range AllServerDefects {
+		assert.NotEmpty(t, expectedServerMutantFailures[d], "no expected failures defined for defect %q", d)
+	}
+}
+
+func TestContractTestsDetectMutants(t *testing.T) {
+	for defect, expectedFailures := range expectedMutantFailures {
+		defect, expectedFailures := defect, expectedFailures
+		t.Run(string(defect), func(t *testing.T) {
+			t.Parallel()
+			results := runContractTests(t, ServiceOptions{Defect: defect}, exactTestIDFilter(expectedFailures))
+			failed := make(map[string]bool)
+			for _, f := range results.Failures {
+				failed[f.TestID.String()] = true
+			}
+			for _, id := range expectedFailures {
+				assert.True(t, failed[id.String()], "test %q should have failed, but passed or did not run", id)
+			}
+		})
+	}
+}
+
+func TestServerContractTestsDetectMutants(t *testing.T) {
+	for defect, expectedFailures := range expectedServerMutantFailures {
+		defect, expectedFailures := defect, expectedFailures
+		t.Run(string(defect), func(t *testing.T) {
+			t.Parallel()
+			results := runServerContractTests(t, ServerServiceOptions{Defect: defect}, exactTestIDFilter(expectedFailures))
+			failed := make(map[string]bool)
+			for _, f := range results.Failures {
+				failed[f.TestID.String()] = true
+			}
+			for _, id := range expectedFailures {
+				assert.True(t, failed[id.String()], "test %q should have failed, but passed or did not run", id)
+			}
+		})
+	}
+}
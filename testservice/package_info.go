@@ -0,0 +1,9 @@
+// Package testservice is a reference implementation of the test service that the SSE contract
+// tests run against, as described in docs/service_spec.md, built on an SSE client that is
+// included in the package.
+//
+// It serves two purposes. First, the test harness's own CI runs the full test suite against it,
+// so that changes to the tests can be verified without any external services. Second, it is an
+// executable example of the servicedef protocol for anyone writing a test service for their own
+// SSE implementation.
+package testservice
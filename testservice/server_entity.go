@@ -0,0 +1,237 @@
+package testservice
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const serverConnectionQueueSize = 1000
+
+// serverEntity is a single SSE server instance that the test harness has asked us to create. It
+// sends everything it is told to send to every client that is currently connected, and keeps a
+// history of events with IDs so that it can replay them to a client that reconnects.
+type serverEntity struct {
+	heartbeatInterval time.Duration
+	connections       map[*serverConnection]struct{}
+	history           []sentEvent
+	defect            Defect
+	logger            framework.Logger
+	closed            bool
+	lock              sync.Mutex
+}
+
+type serverConnection struct {
+	sendCh    chan []byte
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+type sentEvent struct {
+	id      string
+	encoded []byte
+}
+
+func newServerEntity(params servicedef.CreateServerParams, defect Defect, logger framework.Logger) *serverEntity {
+	e := &serverEntity{
+		connections: make(map[*serverConnection]struct{}),
+		defect:      defect,
+		logger:      logger,
+	}
+	if params.HeartbeatMS.IsDefined() {
+		e.heartbeatInterval = time.Duration(params.HeartbeatMS.IntValue()) * time.Millisecond
+	}
+	return e
+}
+
+func (e *serverEntity) serveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	conn := &serverConnection{
+		sendCh:  make(chan []byte, serverConnectionQueueSize),
+		closeCh: make(chan struct{}),
+	}
+	// Register the connection before we send the response headers, so that as soon as the client
+	// sees the response, any event the test harness asks us to send will be sent to this client.
+	e.lock.Lock()
+	if e.closed {
+		e.lock.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	e.connections[conn] = struct{}{}
+	if lastEventID := r.Header.Get("Last-Event-Id"); lastEventID != "" {
+		e.queueReplay(conn, lastEventID)
+	}
+	e.lock.Unlock()
+	defer func() {
+		e.lock.Lock()
+		delete(e.connections, conn)
+		e.lock.Unlock()
+	}()
+
+	e.logger.Printf("Client connected")
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	var out io.Writer = w
+	if e.defect == DefectAlwaysGzip {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer func() { _ = gz.Close() }()
+		out = gzipFlushingWriter{gz}
+	}
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var heartbeatCh <-chan time.Time
+	if e.heartbeatInterval > 0 {
+		ticker := time.NewTicker(e.heartbeatInterval)
+		defer ticker.Stop()
+		heartbeatCh = ticker.C
+	}
+	for {
+		select {
+		case data := <-conn.sendCh:
+			_, _ = out.Write(data)
+			flusher.Flush()
+		case <-heartbeatCh:
+			_, _ = out.Write([]byte(":\n"))
+			flusher.Flush()
+		case <-conn.closeCh:
+			e.logger.Printf("Closing connection")
+			return
+		case <-r.Context().Done():
+			e.logger.Printf("Client disconnected")
+			return
+		}
+	}
+}
+
+// gzipFlushingWriter flushes each write as a complete gzip block, so that the client can read it
+// without waiting for more data.
+type gzipFlushingWriter struct {
+	gz *gzip.Writer
+}
+
+func (w gzipFlushingWriter) Write(data []byte) (int, error) {
+	n, err := w.gz.Write(data)
+	if err == nil {
+		err = w.gz.Flush()
+	}
+	return n, err
+}
+
+// queueReplay sends every event that came after the one with the specified ID. If we do not know
+// of any such event, we replay nothing, since we cannot tell what the client has missed. The
+// caller must hold the lock.
+func (e *serverEntity) queueReplay(conn *serverConnection, lastEventID string) {
+	for i := len(e.history) - 1; i >= 0; i-- {
+		if e.history[i].id == lastEventID {
+			e.logger.Printf("Replaying %d events after ID %q", len(e.history)-i-1, lastEventID)
+			for _, event := range e.history[i+1:] {
+				conn.sendCh <- event.encoded
+			}
+			return
+		}
+	}
+}
+
+func (e *serverEntity) doCommand(params servicedef.ServerCommandParams) error {
+	switch params.Command {
+	case servicedef.ServerCommandSendEvent:
+		if params.Event == nil {
+			return errors.New("missing event parameters")
+		}
+		encoded := encodeEvent(*params.Event)
+		e.lock.Lock()
+		if params.Event.ID != "" {
+			e.history = append(e.history, sentEvent{id: params.Event.ID, encoded: encoded})
+		}
+		e.broadcast(encoded)
+		e.lock.Unlock()
+	case servicedef.ServerCommandSendComment:
+		if params.Comment == nil {
+			return errors.New("missing comment parameters")
+		}
+		e.lock.Lock()
+		e.broadcast(encodeComment(params.Comment.Text))
+		e.lock.Unlock()
+	case servicedef.ServerCommandSendRetry:
+		if params.Retry == nil {
+			return errors.New("missing retry parameters")
+		}
+		e.lock.Lock()
+		e.broadcast([]byte("retry: " + strconv.Itoa(params.Retry.RetryMS) + "\n"))
+		e.lock.Unlock()
+	case servicedef.ServerCommandCloseConnections:
+		e.closeConnections()
+	default:
+		return fmt.Errorf("unknown command %q", params.Command)
+	}
+	return nil
+}
+
+// broadcast queues data to be sent to every connected client. The caller must hold the lock.
+func (e *serverEntity) broadcast(data []byte) {
+	for conn := range e.connections {
+		conn.sendCh <- data
+	}
+}
+
+func (e *serverEntity) closeConnections() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for conn := range e.connections {
+		conn.closeOnce.Do(func() { close(conn.closeCh) })
+		delete(e.connections, conn)
+	}
+}
+
+func (e *serverEntity) close() {
+	e.closeConnections()
+	e.lock.Lock()
+	e.closed = true
+	e.lock.Unlock()
+}
+
+// encodeEvent produces the SSE representation of an event. Every line of the data, including an
+// empty line at the beginning or end, needs its own "data:" field. The space after each colon is
+// optional in SSE, but we always include it, so that a leading space in the value itself is not
+// mistaken for the optional one and stripped by the client.
+func encodeEvent(event servicedef.ServerEventParams) []byte {
+	var b strings.Builder
+	if event.Type != "" {
+		b.WriteString("event: " + event.Type + "\n")
+	}
+	if event.ID != "" {
+		b.WriteString("id: " + event.ID + "\n")
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(event.Data, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return []byte(b.String())
+}
+
+func encodeComment(text string) []byte {
+	var b strings.Builder
+	text = strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+	for _, line := range strings.Split(text, "\n") {
+		b.WriteString(":" + line + "\n")
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,171 @@
+package testservice
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const (
+	serversPathPrefix = "/servers/"
+	serverStreamPath  = "/stream"
+)
+
+// DefaultServerCapabilities is the list of capabilities that the reference server-side test
+// service reports if ServerServiceOptions.Capabilities is not set.
+var DefaultServerCapabilities = []string{ //nolint:gochecknoglobals
+	"comments",
+	"heartbeat",
+	"last-event-id",
+	"retry",
+}
+
+// ServerServiceOptions contains optional parameters for NewServerService.
+type ServerServiceOptions struct {
+	// Capabilities overrides the list of capabilities that the service reports.
+	Capabilities []string
+
+	// Defect, if set, introduces a deliberate bug into the SSE server. It must be one of
+	// AllServerDefects.
+	Defect Defect
+
+	// Logger receives debug output from the service. If nil, output is discarded.
+	Logger framework.Logger
+
+	// OnStop is called if the test harness tells the service to stop.
+	OnStop func()
+}
+
+// ServerService is the reference implementation of the test service for the server-side test
+// suite, as described in docs/server_service_spec.md. It is an http.Handler that can be served at
+// the root of any HTTP server.
+type ServerService struct {
+	options      ServerServiceOptions
+	capabilities framework.Capabilities
+	servers      map[string]*serverEntity
+	lastServerID int
+	lock         sync.Mutex
+}
+
+// NewServerService creates a ServerService.
+func NewServerService(options ServerServiceOptions) *ServerService {
+	if options.Logger == nil {
+		options.Logger = framework.NullLogger()
+	}
+	capabilities := options.Capabilities
+	if capabilities == nil {
+		capabilities = DefaultServerCapabilities
+	}
+	return &ServerService{
+		options:      options,
+		capabilities: append(framework.Capabilities(nil), capabilities...),
+		servers:      make(map[string]*serverEntity),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *ServerService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		switch r.Method {
+		case "GET", "HEAD":
+			writeJSON(w, http.StatusOK, servicedef.StatusResponse{
+				Name:         "sse-contract-tests reference server service",
+				Capabilities: s.capabilities,
+			})
+		case "POST":
+			s.createServer(w, r)
+		case "DELETE":
+			s.options.Logger.Printf("Test harness asked us to stop")
+			s.Close()
+			w.WriteHeader(http.StatusNoContent)
+			if s.options.OnStop != nil {
+				go s.options.OnStop()
+			}
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case strings.HasPrefix(r.URL.Path, serversPathPrefix):
+		id := strings.TrimPrefix(r.URL.Path, serversPathPrefix)
+		isStream := strings.HasSuffix(id, serverStreamPath)
+		id = strings.TrimSuffix(id, serverStreamPath)
+		e := s.getServer(id)
+		switch {
+		case e == nil:
+			w.WriteHeader(http.StatusNotFound)
+		case isStream && r.Method == "GET":
+			e.serveStream(w, r)
+		case !isStream && r.Method == "POST":
+			var params servicedef.ServerCommandParams
+			if !readJSON(w, r, &params) {
+				return
+			}
+			if err := e.doCommand(params); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		case !isStream && r.Method == "DELETE":
+			s.lock.Lock()
+			delete(s.servers, id)
+			s.lock.Unlock()
+			e.close()
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Close stops all active SSE servers.
+func (s *ServerService) Close() {
+	s.lock.Lock()
+	servers := s.servers
+	s.servers = make(map[string]*serverEntity)
+	s.lock.Unlock()
+	for _, e := range servers {
+		e.close()
+	}
+}
+
+func (s *ServerService) createServer(w http.ResponseWriter, r *http.Request) {
+	var params servicedef.CreateServerParams
+	if !readJSON(w, r, &params) {
+		return
+	}
+
+	s.lock.Lock()
+	s.lastServerID++
+	id := strconv.Itoa(s.lastServerID)
+	s.lock.Unlock()
+
+	logger := framework.LoggerWithPrefix(s.options.Logger, fmt.Sprintf("[%s %s]: ", id, params.Tag))
+	e := newServerEntity(params, s.options.Defect, logger)
+
+	s.lock.Lock()
+	s.servers[id] = e
+	s.lock.Unlock()
+
+	resourcePath := serversPathPrefix + id
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	w.Header().Set("Location", resourcePath)
+	writeJSON(w, http.StatusCreated, servicedef.CreateServerResponse{
+		StreamURL: fmt.Sprintf("%s://%s%s%s", scheme, r.Host, resourcePath, serverStreamPath),
+	})
+}
+
+func (s *ServerService) getServer(id string) *serverEntity {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.servers[id]
+}
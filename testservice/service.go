@@ -0,0 +1,242 @@
+package testservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const streamsPathPrefix = "/streams/"
+
+// DefaultCapabilities is the list of capabilities that the reference test service reports if
+// ServiceOptions.Capabilities is not set. It includes every optional feature except
+// "event-type-listeners", since that one makes the client less capable rather than more, and
+// "callback-stream" and "callback-poll", since those are alternative ways of delivering callbacks
+// rather than features of the client.
+var DefaultCapabilities = []string{ //nolint:gochecknoglobals
+	"bom",
+	"callback-batch",
+	"comments",
+	"compression-deflate",
+	"compression-gzip",
+	"connection-state",
+	"data-base64",
+	"dynamic-request-params",
+	"error-categories",
+	"headers",
+	"last-event-id",
+	"post",
+	"proxy",
+	"read-timeout",
+	"report",
+	"restart",
+	"server-directed-shutdown-request",
+}
+
+// ServiceOptions contains optional parameters for NewService.
+type ServiceOptions struct {
+	// Capabilities overrides the list of capabilities that the service reports and implements.
+	Capabilities []string
+
+	// Defect, if set, introduces a deliberate bug into the SSE client. See Defect.
+	Defect Defect
+
+	// Logger receives debug output from the service. If nil, output is discarded.
+	Logger framework.Logger
+
+	// OnStop is called if the test harness tells the service to stop.
+	OnStop func()
+}
+
+// Service is the reference implementation of the test service described in docs/service_spec.md.
+// It is an http.Handler that can be served at the root of any HTTP server.
+type Service struct {
+	options      ServiceOptions
+	capabilities framework.Capabilities
+	streams      map[string]*streamEntity
+	lastStreamID int
+	lock         sync.Mutex
+}
+
+// NewService creates a Service.
+func NewService(options ServiceOptions) *Service {
+	if options.Logger == nil {
+		options.Logger = framework.NullLogger()
+	}
+	capabilities := options.Capabilities
+	if capabilities == nil {
+		capabilities = DefaultCapabilities
+	}
+	return &Service{
+		options:      options,
+		capabilities: append(framework.Capabilities(nil), capabilities...),
+		streams:      make(map[string]*streamEntity),
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Service) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/":
+		switch r.Method {
+		case "GET", "HEAD":
+			s.getStatus(w)
+		case "POST":
+			s.createStream(w, r)
+		case "DELETE":
+			s.stop(w)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	case strings.HasPrefix(r.URL.Path, streamsPathPrefix):
+		id := strings.TrimPrefix(r.URL.Path, streamsPathPrefix)
+		if strings.HasSuffix(id, servicedef.PolledMessagesPath) {
+			s.getPolledMessages(w, r, strings.TrimSuffix(id, servicedef.PolledMessagesPath))
+			return
+		}
+		switch r.Method {
+		case "POST":
+			s.sendCommand(w, r, id)
+		case "DELETE":
+			s.closeStream(w, id)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// Close stops all active streams.
+func (s *Service) Close() {
+	s.lock.Lock()
+	streams := s.streams
+	s.streams = make(map[string]*streamEntity)
+	s.lock.Unlock()
+	for _, e := range streams {
+		e.close()
+	}
+}
+
+func (s *Service) getStatus(w http.ResponseWriter) {
+	writeJSON(w, http.StatusOK, servicedef.StatusResponse{
+		Name:         "sse-contract-tests reference service",
+		Capabilities: s.capabilities,
+	})
+}
+
+func (s *Service) stop(w http.ResponseWriter) {
+	s.options.Logger.Printf("Test harness asked us to stop")
+	s.Close()
+	w.WriteHeader(http.StatusNoContent)
+	if s.options.OnStop != nil {
+		go s.options.OnStop()
+	}
+}
+
+func (s *Service) createStream(w http.ResponseWriter, r *http.Request) {
+	var params servicedef.CreateStreamParams
+	if !readJSON(w, r, &params) {
+		return
+	}
+
+	s.lock.Lock()
+	s.lastStreamID++
+	id := strconv.Itoa(s.lastStreamID)
+	s.lock.Unlock()
+
+	logger := framework.LoggerWithPrefix(s.options.Logger, fmt.Sprintf("[%s %s]: ", id, params.Tag))
+	e, err := newStreamEntity(params, s.capabilities, s.options.Defect, logger)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.Printf("Started stream for %s", params.StreamURL)
+
+	s.lock.Lock()
+	s.streams[id] = e
+	s.lock.Unlock()
+
+	w.Header().Set("Location", streamsPathPrefix+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *Service) sendCommand(w http.ResponseWriter, r *http.Request, id string) {
+	e := s.getStream(id)
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var params servicedef.CommandParams
+	if !readJSON(w, r, &params) {
+		return
+	}
+	if err := e.doCommand(params); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Service) getPolledMessages(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	e := s.getStream(id)
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	after, err := strconv.Atoi(r.URL.Query().Get("after"))
+	if err != nil {
+		http.Error(w, "invalid or missing 'after' parameter", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, e.callbacks.messagesAfter(after))
+}
+
+func (s *Service) closeStream(w http.ResponseWriter, id string) {
+	s.lock.Lock()
+	e := s.streams[id]
+	delete(s.streams, id)
+	s.lock.Unlock()
+	if e == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	e.close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) getStream(id string) *streamEntity {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.streams[id]
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, out interface{}) bool {
+	data, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(data, out)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid request body: %s", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	data, _ := json.Marshal(value)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
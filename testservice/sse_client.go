@@ -0,0 +1,361 @@
+package testservice
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const (
+	defaultReconnectDelay = time.Second
+	readBufferSize        = 8192
+)
+
+const maxRedirects = 10
+
+var (
+	errReadTimeout      = errors.New("read timeout")
+	errTooManyRedirects = errors.New("too many redirects")
+)
+
+// sseClientConfig contains everything the SSE client needs to know in order to make its requests.
+type sseClientConfig struct {
+	url            string
+	method         string
+	body           string
+	headers        map[string]string
+	lastEventID    string
+	reconnectDelay time.Duration
+	readTimeout    time.Duration
+	reportState    bool
+	proxyURL       *url.URL
+	defect         Defect
+}
+
+// sseClient is the SSE implementation that the reference test service exercises. It follows the
+// processing model of the SSE specification: it connects, parses the stream, and reconnects after
+// any failure that the spec does not define as fatal, sending the last event ID that it saw.
+type sseClient struct {
+	config        sseClientConfig
+	httpClient    *http.Client
+	output        func(servicedef.CallbackMessage)
+	logger        framework.Logger
+	lastEventID   string
+	attempts      int
+	cancelCurrent context.CancelFunc
+	restarting    bool
+	closed        bool
+	closeCh       chan struct{}
+	lock          sync.Mutex
+}
+
+// connectionResult describes why a connection attempt ended.
+type connectionResult int
+
+const (
+	resultRetry connectionResult = iota
+	resultRetryImmediately
+	resultStop
+)
+
+func newSSEClient(
+	config sseClientConfig,
+	output func(servicedef.CallbackMessage),
+	logger framework.Logger,
+) *sseClient {
+	httpClient := &http.Client{CheckRedirect: checkRedirect}
+	if config.proxyURL != nil {
+		httpClient.Transport = &http.Transport{Proxy: http.ProxyURL(config.proxyURL)}
+	}
+	return &sseClient{
+		config:      config,
+		httpClient:  httpClient,
+		output:      output,
+		logger:      logger,
+		lastEventID: config.lastEventID,
+		closeCh:     make(chan struct{}),
+	}
+}
+
+func (c *sseClient) start() {
+	go c.run()
+}
+
+func (c *sseClient) run() {
+	for {
+		result := c.connectAndRead()
+		if result == resultStop || c.isClosed() {
+			return
+		}
+		if result == resultRetryImmediately {
+			continue
+		}
+		delay := c.config.reconnectDelay
+		c.logger.Printf("Reconnecting in %s", delay)
+		select {
+		case <-time.After(delay):
+		case <-c.closeCh:
+			return
+		}
+	}
+}
+
+// restart drops the current connection, if any, and reconnects without waiting.
+func (c *sseClient) restart() {
+	c.lock.Lock()
+	c.restarting = true
+	cancel := c.cancelCurrent
+	c.lock.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// close permanently stops the client.
+func (c *sseClient) close() {
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return
+	}
+	c.closed = true
+	close(c.closeCh)
+	cancel := c.cancelCurrent
+	c.lock.Unlock()
+	if cancel != nil && c.config.defect != DefectKeepConnectionOnClose {
+		cancel()
+	}
+}
+
+// setHeaders replaces the custom request headers. Like setURL, it does not affect the current
+// connection, only the next one.
+func (c *sseClient) setHeaders(headers map[string]string) {
+	c.lock.Lock()
+	c.config.headers = headers
+	c.lock.Unlock()
+}
+
+// setURL changes the stream URL for the next connection.
+func (c *sseClient) setURL(streamURL string) {
+	c.lock.Lock()
+	c.config.url = streamURL
+	c.lock.Unlock()
+}
+
+func (c *sseClient) isClosed() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.closed
+}
+
+func (c *sseClient) connectAndRead() connectionResult {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.lock.Lock()
+	if c.closed {
+		c.lock.Unlock()
+		return resultStop
+	}
+	c.cancelCurrent = cancel
+	c.restarting = false
+	streamURL, headers := c.config.url, c.config.headers
+	c.lock.Unlock()
+
+	var body io.Reader
+	if c.config.body != "" {
+		body = strings.NewReader(c.config.body)
+	}
+	method := c.config.method
+	if method == "" {
+		method = "GET"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, streamURL, body)
+	if err != nil {
+		c.reportError(fmt.Errorf("invalid request: %w", err), "", 0, false)
+		return resultStop
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	// Setting this ourselves stops Go's HTTP client from decompressing gzip transparently, so that
+	// we handle both encodings the same way in decompressBody.
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	if c.config.defect == DefectDropQueryOnReconnect && c.attempts > 0 {
+		req.URL.RawQuery = ""
+	}
+	if c.lastEventID != "" && !(c.config.defect == DefectNoLastEventID && c.attempts > 0) {
+		req.Header.Set("Last-Event-Id", c.lastEventID)
+	}
+	c.attempts++
+
+	c.logger.Printf("Connecting to %s", streamURL)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return c.connectionEnded(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if c.config.defect == DefectOpenedBeforeStatusCheck {
+		c.reportState(servicedef.CallbackKindOpened)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		c.reportError(errors.New("server returned HTTP 204; not reconnecting"),
+			servicedef.ErrorCategoryHTTPStatus, resp.StatusCode, false)
+		return resultStop
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		// Go's HTTP client follows redirects itself, so if we get one here, it had no usable Location.
+		c.reportError(fmt.Errorf("server returned HTTP %d without a valid Location", resp.StatusCode),
+			servicedef.ErrorCategoryRedirect, resp.StatusCode, true)
+		return resultRetry
+	case resp.StatusCode != http.StatusOK:
+		c.reportError(fmt.Errorf("server returned HTTP %d", resp.StatusCode),
+			servicedef.ErrorCategoryHTTPStatus, resp.StatusCode, true)
+		return resultRetry
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err != nil ||
+		mediaType != "text/event-stream" {
+		c.reportError(fmt.Errorf("server returned invalid content type %q", resp.Header.Get("Content-Type")),
+			servicedef.ErrorCategoryInvalidContentType, 0, false)
+		if c.config.defect != DefectIgnoreContentType {
+			return resultStop
+		}
+	}
+
+	stream, err := decompressBody(resp)
+	if err != nil {
+		return c.connectionEnded(err)
+	}
+	if c.config.defect != DefectOpenedBeforeStatusCheck {
+		c.reportState(servicedef.CallbackKindOpened)
+	}
+	parser := newSSEParser(c.lastEventID, c.config.defect, c)
+	err = c.readStream(stream, parser, cancel)
+	parser.end()
+	if !c.isClosed() {
+		c.reportState(servicedef.CallbackKindClosed)
+	}
+	c.lastEventID = parser.lastEventID
+	if parser.retry >= 0 {
+		c.config.reconnectDelay = time.Duration(parser.retry) * time.Millisecond
+	}
+	return c.connectionEnded(err)
+}
+
+// decompressBody returns a reader for the response body that undoes any Content-Encoding. Creating
+// a gzip or zlib reader reads the compression header, so it blocks until the server sends that.
+func decompressBody(resp *http.Response) (io.Reader, error) {
+	switch encoding := strings.ToLower(resp.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+		return resp.Body, nil
+	case "gzip":
+		return gzip.NewReader(resp.Body)
+	case "deflate":
+		return zlib.NewReader(resp.Body)
+	default:
+		return nil, fmt.Errorf("server used unsupported content encoding %q", encoding)
+	}
+}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return errTooManyRedirects
+	}
+	return nil
+}
+
+func (c *sseClient) readStream(body io.Reader, parser *sseParser, cancel context.CancelFunc) error {
+	var timedOut bool
+	var timeoutLock sync.Mutex
+	var timer *time.Timer
+	if c.config.readTimeout > 0 {
+		timer = time.AfterFunc(c.config.readTimeout, func() {
+			timeoutLock.Lock()
+			timedOut = true
+			timeoutLock.Unlock()
+			cancel()
+		})
+		defer timer.Stop()
+	}
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if timer != nil && (c.config.defect != DefectTimeoutResetOnlyByLines ||
+				bytes.ContainsAny(buf[:n], "\r\n")) {
+				timer.Reset(c.config.readTimeout)
+			}
+			parser.feed(buf[:n])
+		}
+		if err != nil {
+			timeoutLock.Lock()
+			defer timeoutLock.Unlock()
+			if timedOut {
+				return errReadTimeout
+			}
+			return err
+		}
+	}
+}
+
+func (c *sseClient) connectionEnded(err error) connectionResult {
+	c.lock.Lock()
+	restarting, closed := c.restarting, c.closed
+	c.lock.Unlock()
+	switch {
+	case closed:
+		return resultStop
+	case restarting:
+		c.logger.Printf("Restarting stream as requested")
+		return resultRetryImmediately
+	case err == nil || errors.Is(err, io.EOF):
+		c.reportError(errors.New("stream closed by server"), servicedef.ErrorCategoryIO, 0, true)
+	case errors.Is(err, errTooManyRedirects):
+		c.reportError(err, servicedef.ErrorCategoryRedirect, 0, true)
+	case errors.Is(err, errReadTimeout):
+		c.reportError(err, servicedef.ErrorCategoryTimeout, 0, true)
+	default:
+		c.reportError(err, servicedef.ErrorCategoryIO, 0, true)
+	}
+	return resultRetry
+}
+
+func (c *sseClient) reportError(err error, category string, status int, willRetry bool) {
+	c.logger.Printf("Stream error: %s", err)
+	m := servicedef.CallbackMessage{Kind: servicedef.CallbackKindError, Error: err.Error()}
+	if category != "" {
+		m.ErrorCategory = category
+		m.HTTPStatus = status
+		m.WillRetry = &willRetry
+	}
+	c.output(m)
+}
+
+func (c *sseClient) reportState(kind string) {
+	if c.config.reportState {
+		c.output(servicedef.CallbackMessage{Kind: kind})
+	}
+}
+
+func (c *sseClient) onEvent(event servicedef.CallbackEvent) {
+	c.output(servicedef.CallbackMessage{Kind: servicedef.CallbackKindEvent, Event: &event})
+}
+
+func (c *sseClient) onComment(comment string) {
+	c.output(servicedef.CallbackMessage{Kind: servicedef.CallbackKindComment, Comment: comment})
+}
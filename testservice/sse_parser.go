@@ -0,0 +1,240 @@
+package testservice
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+const byteOrderMark = '\uFEFF'
+
+// sseParser implements the stream parsing rules of the SSE specification:
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+//
+// A parser is only used for a single connection; reconnecting means starting a new stream, so
+// partially received data must be discarded. The only state that survives a reconnection is the
+// last event ID, which the caller passes in and reads back out.
+type sseParser struct {
+	decoder     utf8StreamDecoder
+	started     bool
+	lastWasCR   bool
+	line        strings.Builder
+	data        strings.Builder
+	hasData     bool
+	eventType   string
+	idBuffer    string
+	lastEventID string
+	retry       int
+	defect      Defect
+	held        []servicedef.CallbackEvent // only used with DefectDispatchOnNextChunk
+	handler     sseParserHandler
+}
+
+type sseParserHandler interface {
+	onEvent(servicedef.CallbackEvent)
+	onComment(string)
+}
+
+func newSSEParser(lastEventID string, defect Defect, handler sseParserHandler) *sseParser {
+	return &sseParser{idBuffer: lastEventID, lastEventID: lastEventID, retry: -1, defect: defect, handler: handler}
+}
+
+// feed consumes the next chunk of bytes from the stream.
+func (p *sseParser) feed(chunk []byte) {
+	if p.defect == DefectDispatchOnNextChunk {
+		for _, event := range p.held {
+			p.handler.onEvent(event)
+		}
+		p.held = nil
+	}
+	text := p.decoder.decode(chunk)
+	if !p.started && text != "" {
+		p.started = true
+		if r, size := utf8.DecodeRuneInString(text); r == byteOrderMark && p.defect != DefectKeepBOM {
+			text = text[size:]
+		}
+	}
+	for text != "" {
+		if p.lastWasCR {
+			p.lastWasCR = false
+			if text[0] == '\n' {
+				text = text[1:]
+				continue
+			}
+		}
+		pos := strings.IndexAny(text, p.lineEndings())
+		if pos < 0 {
+			p.line.WriteString(text)
+			return
+		}
+		p.line.WriteString(text[:pos])
+		p.lastWasCR = text[pos] == '\r'
+		text = text[pos+1:]
+		line := p.line.String()
+		p.line.Reset()
+		p.processLine(line)
+	}
+}
+
+// end is called when the stream has ended. An event that has not been terminated by a blank line
+// is discarded.
+func (p *sseParser) end() {
+	if p.defect == DefectDispatchIncompleteEvent {
+		if p.line.Len() > 0 {
+			line := p.line.String()
+			p.line.Reset()
+			p.processLine(line)
+		}
+		p.dispatchEvent()
+	}
+}
+
+func (p *sseParser) lineEndings() string {
+	if p.defect == DefectIgnoreCR {
+		return "\n"
+	}
+	return "\r\n"
+}
+
+func (p *sseParser) processLine(line string) {
+	if p.defect == DefectIgnoreCR {
+		line = strings.TrimSuffix(line, "\r")
+	}
+	if line == "" {
+		p.dispatchEvent()
+		return
+	}
+	if line[0] == ':' {
+		p.handler.onComment(line[1:])
+		return
+	}
+	var name, value string
+	if colon := strings.IndexByte(line, ':'); colon >= 0 {
+		name, value = line[:colon], line[colon+1:]
+		if p.defect == DefectStripAllLeadingSpaces {
+			value = strings.TrimLeft(value, " ")
+		} else {
+			value = strings.TrimPrefix(value, " ")
+		}
+	} else {
+		name = line
+	}
+	switch name {
+	case "event":
+		p.eventType = value
+	case "data":
+		p.data.WriteString(value)
+		p.data.WriteByte('\n')
+		p.hasData = true
+	case "id":
+		if !strings.ContainsRune(value, 0) || p.defect == DefectAcceptIDWithNull {
+			p.idBuffer = value
+		}
+	case "retry":
+		if value != "" && strings.Trim(value, "0123456789") == "" {
+			if n, err := strconv.Atoi(value); err == nil {
+				p.retry = n
+			}
+		}
+	}
+}
+
+func (p *sseParser) dispatchEvent() {
+	// The ID from an "id:" field only becomes the last event ID once its event is dispatched, so
+	// an ID in an incomplete event will not be sent when reconnecting.
+	p.lastEventID = p.idBuffer
+	if !p.hasData {
+		p.eventType = ""
+		return
+	}
+	data := strings.TrimSuffix(p.data.String(), "\n")
+	event := servicedef.CallbackEvent{Type: p.eventType, Data: data, ID: p.lastEventID}
+	if event.Type == "" {
+		event.Type = "message"
+	}
+	p.data.Reset()
+	p.hasData = false
+	p.eventType = ""
+	if p.defect == DefectDispatchOnNextChunk {
+		p.held = append(p.held, event)
+		return
+	}
+	p.handler.onEvent(event)
+}
+
+// utf8StreamDecoder implements the UTF-8 decode algorithm from the WHATWG Encoding standard, in
+// which each maximal invalid subsequence is replaced by a single U+FFFD. Go's own conversions
+// replace each invalid byte individually, which gives different results for truncated sequences.
+type utf8StreamDecoder struct {
+	pending []byte
+}
+
+func (d *utf8StreamDecoder) decode(chunk []byte) string {
+	buf := chunk
+	if len(d.pending) > 0 {
+		buf = append(d.pending, chunk...)
+		d.pending = nil
+	}
+	var out strings.Builder
+	out.Grow(len(buf))
+	start := 0 // start of the current run of valid bytes that we can copy as-is
+	for i := 0; i < len(buf); {
+		if buf[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if r, size := utf8.DecodeRune(buf[i:]); r != utf8.RuneError || size > 1 {
+			i += size
+			continue
+		}
+		out.Write(buf[start:i])
+		n, incomplete := utf8ValidPrefixLength(buf[i:])
+		if incomplete {
+			// This might be the start of a valid character whose remaining bytes are in the next chunk.
+			d.pending = append([]byte(nil), buf[i:]...)
+			return out.String()
+		}
+		out.WriteRune(utf8.RuneError)
+		i += n
+		start = i
+	}
+	out.Write(buf[start:])
+	return out.String()
+}
+
+// utf8ValidPrefixLength is called when b does not start with a valid UTF-8 sequence. It returns
+// the length of the longest prefix of b that could begin a valid sequence (at least 1), and whether
+// that prefix runs to the end of b, so that more bytes might still complete it.
+func utf8ValidPrefixLength(b []byte) (int, bool) {
+	lead := b[0]
+	lower, upper := byte(0x80), byte(0xBF)
+	var needed int
+	switch {
+	case lead >= 0xC2 && lead <= 0xDF:
+		needed = 1
+	case lead == 0xE0:
+		needed, lower = 2, 0xA0
+	case lead == 0xED:
+		needed, upper = 2, 0x9F
+	case lead >= 0xE1 && lead <= 0xEF:
+		needed = 2
+	case lead == 0xF0:
+		needed, lower = 3, 0x90
+	case lead == 0xF4:
+		needed, upper = 3, 0x8F
+	case lead >= 0xF1 && lead <= 0xF3:
+		needed = 3
+	default:
+		return 1, false
+	}
+	n := 1
+	for ; n <= needed && n < len(b); n++ {
+		if b[n] < lower || b[n] > upper {
+			break
+		}
+		lower, upper = 0x80, 0xBF
+	}
+	return n, n == len(b)
+}
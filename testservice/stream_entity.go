@@ -0,0 +1,124 @@
+package testservice
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/servicedef"
+)
+
+// streamEntity is a single SSE client instance that the test harness has asked us to create.
+type streamEntity struct {
+	client         *sseClient
+	callbacks      *callbackSender
+	filterTypes    bool
+	encodeData     bool
+	listeningTypes map[string]bool
+	logger         framework.Logger
+	lock           sync.Mutex
+}
+
+func newStreamEntity(
+	params servicedef.CreateStreamParams,
+	capabilities framework.Capabilities,
+	defect Defect,
+	logger framework.Logger,
+) (*streamEntity, error) {
+	if params.StreamURL == "" {
+		return nil, errors.New("streamUrl is required")
+	}
+	if params.CallbackURL == "" && params.CallbackTransport != servicedef.CallbackTransportPoll {
+		return nil, errors.New("callbackUrl is required")
+	}
+	config := sseClientConfig{
+		url:            params.StreamURL,
+		method:         params.Method,
+		body:           params.Body,
+		headers:        params.Headers,
+		lastEventID:    params.LastEventID,
+		reconnectDelay: defaultReconnectDelay,
+		reportState:    params.ReportConnectionState,
+		defect:         defect,
+	}
+	if params.InitialDelayMS.IsDefined() {
+		config.reconnectDelay = time.Duration(params.InitialDelayMS.IntValue()) * time.Millisecond
+	}
+	if params.ProxyURL != "" {
+		u, err := url.Parse(params.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("proxyUrl is not valid: %w", err)
+		}
+		config.proxyURL = u
+	}
+	if params.ReadTimeoutMS.IsDefined() {
+		config.readTimeout = time.Duration(params.ReadTimeoutMS.IntValue()) * time.Millisecond
+	}
+
+	batchCallbacks := capabilities.Has("callback-batch")
+	callbacks := newCallbackSender(params.CallbackURL, params.CallbackTransport, batchCallbacks, logger)
+	e := &streamEntity{
+		callbacks:      callbacks,
+		filterTypes:    capabilities.Has("event-type-listeners"),
+		encodeData:     capabilities.Has("data-base64"),
+		listeningTypes: map[string]bool{"message": true},
+		logger:         logger,
+	}
+	e.client = newSSEClient(config, e.handleMessage, logger)
+	e.client.start()
+	return e, nil
+}
+
+func (e *streamEntity) handleMessage(message servicedef.CallbackMessage) {
+	if message.Kind == servicedef.CallbackKindEvent && e.filterTypes {
+		e.lock.Lock()
+		listening := e.listeningTypes[message.Event.Type]
+		e.lock.Unlock()
+		if !listening {
+			e.logger.Printf("Ignoring event of type %q since we are not listening for it", message.Event.Type)
+			return
+		}
+	}
+	if message.Kind == servicedef.CallbackKindEvent && e.encodeData {
+		event := *message.Event
+		event.DataBase64 = base64.StdEncoding.EncodeToString([]byte(event.Data))
+		message.Event = &event
+	}
+	e.callbacks.send(message)
+}
+
+func (e *streamEntity) doCommand(params servicedef.CommandParams) error {
+	switch params.Command {
+	case servicedef.CommandListen:
+		if params.Listen == nil {
+			return errors.New("missing listen parameters")
+		}
+		e.lock.Lock()
+		e.listeningTypes[params.Listen.Type] = true
+		e.lock.Unlock()
+	case servicedef.CommandRestart:
+		e.client.restart()
+	case servicedef.CommandSetHeaders:
+		if params.SetHeaders == nil {
+			return errors.New("missing setHeaders parameters")
+		}
+		e.client.setHeaders(params.SetHeaders.Headers)
+	case servicedef.CommandSetURL:
+		if params.SetURL == nil || params.SetURL.StreamURL == "" {
+			return errors.New("missing setUrl parameters")
+		}
+		e.client.setURL(params.SetURL.StreamURL)
+	default:
+		return fmt.Errorf("unknown command %q", params.Command)
+	}
+	return nil
+}
+
+func (e *streamEntity) close() {
+	e.client.close()
+	e.callbacks.close()
+}
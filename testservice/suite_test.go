@@ -0,0 +1,179 @@
+package testservice
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/launchdarkly/sse-contract-tests/framework"
+	"github.com/launchdarkly/sse-contract-tests/framework/harness"
+	"github.com/launchdarkly/sse-contract-tests/framework/ldtest"
+	"github.com/launchdarkly/sse-contract-tests/sseservertests"
+	"github.com/launchdarkly/sse-contract-tests/ssetests"
+
+	"github.com/stretchr/testify/require"
+)
+
+// goTestLogger reports the progress of a contract test run through Go's test output.
+type goTestLogger struct {
+	t *testing.T
+}
+
+func (l goTestLogger) TestStarted(ldtest.TestID) {}
+
+func (l goTestLogger) TestError(id ldtest.TestID, err error) {
+	l.t.Logf("[%s]: %s", id, err)
+}
+
+func (l goTestLogger) TestFinished(id ldtest.TestID, failed bool, debugOutput framework.CapturedOutput) {
+	if failed && len(debugOutput) > 0 {
+		l.t.Logf("[%s] debug output:\n%s", id, debugOutput.ToString("  "))
+	}
+}
+
+func (l goTestLogger) TestSkipped(ldtest.TestID, string) {}
+
+func (l goTestLogger) TestReport(id ldtest.TestID, report string) {
+	l.t.Logf("[%s]: %s", id, report)
+}
+
+func freePort(t *testing.T) int {
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	require.NoError(t, listener.Close())
+	return port
+}
+
+// newTestHarness creates a test harness for a test service that is already running. The test
+// harness's HTTP listener stays open for the rest of the process, since the harness has no way to
+// shut it down, so each call uses a new port.
+func newTestHarness(t *testing.T, serviceURL string) *harness.TestHarness {
+	h, err := harness.NewTestHarness(
+		serviceURL,
+		"localhost",
+		freePort(t),
+		time.Second*10,
+		framework.NullLogger(),
+		ioutil.Discard,
+	)
+	require.NoError(t, err)
+	return h
+}
+
+// runContractTests runs the SSE client contract test suite against a new instance of the
+// reference test service.
+func runContractTests(t *testing.T, options ServiceOptions, filter ldtest.Filter) ldtest.Results {
+	service := NewService(options)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	defer service.Close()
+
+	return ssetests.RunTestSuite(newTestHarness(t, server.URL), filter, goTestLogger{t})
+}
+
+// runServerContractTests runs the SSE server contract test suite against a new instance of the
+// reference server-side test service.
+func runServerContractTests(t *testing.T, options ServerServiceOptions, filter ldtest.Filter) ldtest.Results {
+	service := NewServerService(options)
+	server := httptest.NewServer(service)
+	defer server.Close()
+	defer service.Close()
+
+	return sseservertests.RunTestSuite(newTestHarness(t, server.URL), filter, goTestLogger{t})
+}
+
+func TestReferenceServicePassesAllContractTests(t *testing.T) {
+	results := runContractTests(t, ServiceOptions{}, nil)
+	for _, f := range results.Failures {
+		t.Errorf("contract test failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}
+
+func TestReferenceServiceWithoutBatchedCallbacks(t *testing.T) {
+	var capabilities []string
+	for _, c := range DefaultCapabilities {
+		if c != "callback-batch" {
+			capabilities = append(capabilities, c)
+		}
+	}
+	results := runContractTests(t, ServiceOptions{Capabilities: capabilities},
+		exactTestIDFilter([]ldtest.TestID{{"basic parsing"}}))
+	for _, f := range results.Failures {
+		t.Errorf("contract test failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}
+
+func TestReferenceServiceWithOtherCallbackTransports(t *testing.T) {
+	for _, capability := range []string{"callback-stream", "callback-poll"} {
+		t.Run(capability, func(t *testing.T) {
+			capabilities := append([]string{capability}, DefaultCapabilities...)
+			results := runContractTests(t, ServiceOptions{Capabilities: capabilities},
+				exactTestIDFilter([]ldtest.TestID{{"basic parsing"}, {"reconnection"}}))
+			for _, f := range results.Failures {
+				t.Errorf("contract test failed: %s", f.TestID)
+			}
+			require.NotEmpty(t, results.Tests)
+		})
+	}
+}
+
+func TestReferenceServerServicePassesAllContractTests(t *testing.T) {
+	results := runServerContractTests(t, ServerServiceOptions{}, nil)
+	for _, f := range results.Failures {
+		t.Errorf("contract test failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}
+
+func TestReferenceServicePassesReplays(t *testing.T) {
+	replays, err := ssetests.LoadReplays("testdata/captures")
+	require.NoError(t, err)
+
+	service := NewService(ServiceOptions{})
+	server := httptest.NewServer(service)
+	defer server.Close()
+	defer service.Close()
+
+	results := ssetests.RunReplaySuite(newTestHarness(t, server.URL), replays, nil, goTestLogger{t})
+	for _, f := range results.Failures {
+		t.Errorf("replay failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}
+
+func TestReferenceServicePassesScenarioFiles(t *testing.T) {
+	files, err := ssetests.LoadScenarioFiles(os.DirFS("testdata/scenarios"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	service := NewService(ServiceOptions{})
+	server := httptest.NewServer(service)
+	defer server.Close()
+	defer service.Close()
+
+	results := ssetests.RunScenarioSuite(newTestHarness(t, server.URL), files, nil, goTestLogger{t})
+	for _, f := range results.Failures {
+		t.Errorf("scenario failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}
+
+func TestReferenceServicePassesStressTests(t *testing.T) {
+	service := NewService(ServiceOptions{})
+	server := httptest.NewServer(service)
+	defer server.Close()
+	defer service.Close()
+
+	options := ssetests.StressOptions{EventCount: 5000, EventSize: 200}
+	results := ssetests.RunStressSuite(newTestHarness(t, server.URL), options, nil, goTestLogger{t})
+	for _, f := range results.Failures {
+		t.Errorf("stress test failed: %s", f.TestID)
+	}
+	require.NotEmpty(t, results.Tests)
+}